@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strings"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// selfplay plays games of clemens against itself and writes them to a PGN file,
+// e.g. to fit the Win/Draw/Loss model with `cmd/wdlfit`.
+// Every game starts with random moves, so the games differ, and all other moves are searched with a node limit.
+// The score and the depth of the search are added to every searched move as a comment in the format of cutechess-cli.
+// Clear results are adjudicated with the same rules as the games of scripts/elo.sh.
+
+var (
+	games       int
+	nodes       uint64
+	randomPlies int
+	seed        uint64
+	pgnFile     string
+)
+
+const (
+	// maxPlies is the length of a game, after which it is not finished.
+	maxPlies = 600
+	// A game is won, if the score is above resignScore for resignPlies plies.
+	resignScore = 1000
+	resignPlies = 8
+	// A game is drawn, if the score is within drawScore for drawPlies plies after drawMinPly.
+	drawScore  = 10
+	drawPlies  = 16
+	drawMinPly = 80
+)
+
+func init() {
+	flag.IntVar(&games, "games", 100, "number of games")
+	flag.Uint64Var(&nodes, "nodes", 5000, "number of nodes searched per move")
+	flag.IntVar(&randomPlies, "random", 8, "number of random plies at the beginning of every game")
+	flag.Uint64Var(&seed, "seed", 1, "seed for the random plies")
+	flag.StringVar(&pgnFile, "pgn", "selfplay.pgn", "PGN file for the games")
+}
+
+func main() {
+	flag.Parse()
+	f, err := os.Create(pgnFile)
+	if err != nil {
+		fmt.Printf("Unable to create pgn file, %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	tables := search.NewTables(16, 16)
+	rng := rand.New(rand.NewPCG(seed, 0))
+	for i := range games {
+		tables.TT.Reset()
+		tables.EvalCache.Clear()
+		g := play(tables, rng)
+		if err := g.writePGN(w, i+1); err != nil {
+			fmt.Printf("Unable to write pgn file, %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Game %v: %v\n", i+1, g.result)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Printf("Unable to write pgn file, %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// game is a played game with its moves in the Standard Algebraic Notation.
+type game struct {
+	moves []string
+	// comments are the comments of the moves, which are empty for the random moves.
+	comments []string
+	result   string
+}
+
+// play plays a game from the start position.
+func play(tables search.Tables, rng *rand.Rand) game {
+	var g game
+	pos := position.New()
+	s := search.NewSearch(*pos, tables)
+	var uciMoves []string
+	repetitions := map[uint64]int{pos.ZobristHash: 1}
+	var resignCount, drawCount int
+	for ply := 0; ply < maxPlies; ply++ {
+		legal := pos.LegalMoves()
+		switch {
+		case len(legal) == 0 && pos.IsInCheck(pos.SideToMove):
+			g.result = winner(types.SwitchColor(pos.SideToMove))
+			return g
+		case len(legal) == 0, pos.HalfMoveClock >= 100, repetitions[pos.ZobristHash] >= 3, isInsufficientMaterial(pos):
+			g.result = "1/2-1/2"
+			return g
+		}
+
+		var m move.Move
+		var comment string
+		if ply < randomPlies {
+			m = legal[rng.IntN(len(legal))]
+		} else {
+			if _, err := s.SetPosition(*position.New(), uciMoves); err != nil {
+				panic(err)
+			}
+			m = s.Search(context.Background(), search.SearchParameter{Nodes: nodes})
+			comment = scoreComment(s.Lines[0])
+
+			// The score is from the view of the side to move, so a clear result has alternating signs.
+			score := int(s.Lines[0].Score)
+			if pos.SideToMove == types.BLACK {
+				score = -score
+			}
+			switch {
+			case score >= resignScore:
+				resignCount = max(resignCount, 0) + 1
+			case score <= -resignScore:
+				resignCount = min(resignCount, 0) - 1
+			default:
+				resignCount = 0
+			}
+			if resignCount >= resignPlies {
+				g.result = winner(types.WHITE)
+				return g
+			}
+			if resignCount <= -resignPlies {
+				g.result = winner(types.BLACK)
+				return g
+			}
+			if ply >= drawMinPly && score <= drawScore && score >= -drawScore {
+				drawCount++
+			} else {
+				drawCount = 0
+			}
+			if drawCount >= drawPlies {
+				g.result = "1/2-1/2"
+				return g
+			}
+		}
+
+		g.moves = append(g.moves, pos.SAN(m))
+		g.comments = append(g.comments, comment)
+		uciMoves = append(uciMoves, m.String())
+		pos.MakeMove(m)
+		repetitions[pos.ZobristHash]++
+	}
+	g.result = "*"
+	return g
+}
+
+// scoreComment returns the comment with the score of the side to move in pawns and the depth of the line,
+// e.g. `+0.35/12` or `-M3/9` for a mate.
+func scoreComment(line search.Info) string {
+	if evaluation.IsCheckmateValue(line.Score) {
+		mate := evaluation.MateIn(line.Score)
+		if mate > 0 {
+			return fmt.Sprintf("+M%v/%v", mate, line.Depth)
+		}
+		return fmt.Sprintf("-M%v/%v", -mate, line.Depth)
+	}
+	return fmt.Sprintf("%+.2f/%v", float64(line.Score)/100, line.Depth)
+}
+
+// winner returns the result of a game won by the color.
+func winner(c types.Color) string {
+	if c == types.WHITE {
+		return "1-0"
+	}
+	return "0-1"
+}
+
+// isInsufficientMaterial returns true, if no side is able to checkmate, which are only kings or a single minor piece.
+func isInsufficientMaterial(pos *position.Position) bool {
+	switch pos.AllPieces.PopulationCount() {
+	case 2:
+		return true
+	case 3:
+		for c := types.WHITE; c < types.COLOR_NUMBER; c++ {
+			if pos.PiecesBitboard[c][types.KNIGHT]|pos.PiecesBitboard[c][types.BISHOP] != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writePGN writes the game in the Portable Game Notation.
+func (g game) writePGN(w io.Writer, round int) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Event \"clemens selfplay\"]\n[Round \"%v\"]\n[White \"clemens\"]\n[Black \"clemens\"]\n[Result \"%v\"]\n\n", round, g.result)
+	line := 0
+	for i, san := range g.moves {
+		tokens := []string{san}
+		if i%2 == 0 {
+			tokens[0] = fmt.Sprintf("%v. %v", i/2+1, san)
+		}
+		if i < len(g.comments) && g.comments[i] != "" {
+			tokens = append(tokens, "{"+g.comments[i]+"}")
+		}
+		for _, token := range tokens {
+			if line > 0 && line+len(token) >= 80 {
+				sb.WriteByte('\n')
+				line = 0
+			} else if line > 0 {
+				sb.WriteByte(' ')
+				line++
+			}
+			sb.WriteString(token)
+			line += len(token)
+		}
+	}
+	if line > 0 {
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(g.result)
+	sb.WriteString("\n\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
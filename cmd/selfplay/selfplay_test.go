@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_play(t *testing.T) {
+	nodes = 500
+	randomPlies = 4
+	g := play(search.NewTables(1, 1), rand.New(rand.NewPCG(1, 0)))
+	assert.Contains(t, []string{"1-0", "0-1", "1/2-1/2", "*"}, g.result)
+	require.Greater(t, len(g.moves), randomPlies)
+
+	// All moves are legal and in the Standard Algebraic Notation
+	pos := position.New()
+	for _, san := range g.moves {
+		m, err := pos.MoveFromSAN(san)
+		require.NoError(t, err)
+		assert.Equal(t, san, pos.SAN(m))
+		pos.MakeMove(m)
+	}
+}
+
+func Test_scoreComment(t *testing.T) {
+	assert.Equal(t, "+0.35/12", scoreComment(search.Info{Score: 35, Depth: 12}))
+	assert.Equal(t, "-1.20/7", scoreComment(search.Info{Score: -120, Depth: 7}))
+	assert.Equal(t, "+0.00/1", scoreComment(search.Info{Depth: 1}))
+	assert.Equal(t, "+M2/5", scoreComment(search.Info{Score: evaluation.INF - 3, Depth: 5}))
+	assert.Equal(t, "-M1/4", scoreComment(search.Info{Score: -evaluation.INF + 2, Depth: 4}))
+}
+
+func Test_isInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{fen: "4k3/8/8/8/8/8/8/4K3 w - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/8/4KB2 w - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/8/4KR2 w - - 0 1", want: false},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", want: false},
+		{fen: "4kb2/8/8/8/8/8/8/4KN2 w - - 0 1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, isInsufficientMaterial(pos))
+		})
+	}
+}
+
+func Test_game_writePGN(t *testing.T) {
+	g := game{
+		moves:    []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"},
+		comments: []string{"", "", "+0.35/12", "-0.80/11", "+2.10/12", "-M2/12", "+M1/1"},
+		result:   "1-0",
+	}
+	var b strings.Builder
+	require.NoError(t, g.writePGN(&b, 3))
+	assert.Equal(t, `[Event "clemens selfplay"]
+[Round "3"]
+[White "clemens"]
+[Black "clemens"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 {+0.35/12} Nc6 {-0.80/11} 3. Bc4 {+2.10/12} Nf6 {-M2/12}
+4. Qxf7# {+M1/1} 1-0
+
+`, b.String())
+}
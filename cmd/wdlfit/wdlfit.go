@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// wdlfit fits the parameters of the Win/Draw/Loss model from a PGN file of finished games.
+// Every quiet position of the games is scored and compared with the result of the game.
+// The model is applied to search scores, so the score of a position is the search score of the move played in it,
+// which is read from the comments of cutechess-cli or `cmd/selfplay`, e.g. `{+0.35/12}`.
+// Only moves without such a comment fall back to the static evaluation and positions with a mate score are skipped.
+// The fitted parameters are printed and can be used as evaluation.DefaultWDLModel.
+
+var (
+	pgnFile    string
+	iterations int
+	skipPlies  int
+)
+
+func init() {
+	flag.StringVar(&pgnFile, "pgn", "", "PGN file with finished games")
+	flag.IntVar(&iterations, "iterations", 1000, "maximal number of iterations for the fit")
+	flag.IntVar(&skipPlies, "skip", 16, "number of plies skipped at the beginning of every game")
+}
+
+func main() {
+	flag.Parse()
+	f, err := os.Open(pgnFile)
+	if err != nil {
+		fmt.Printf("Unable to open pgn file, %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	games, err := parsePGN(f)
+	if err != nil {
+		fmt.Printf("Unable to parse pgn file, %v\n", err)
+		os.Exit(1)
+	}
+
+	var samples []evaluation.WDLSample
+	for _, g := range games {
+		s, err := g.samples(skipPlies)
+		if err != nil {
+			fmt.Printf("Skipping game, %v\n", err)
+			continue
+		}
+		samples = append(samples, s...)
+	}
+	fmt.Printf("Games: %v\nSamples: %v\n", len(games), len(samples))
+
+	model := evaluation.FitWDLModel(samples, evaluation.DefaultWDLModel, iterations)
+	fmt.Printf("A: %v\nB: %v\n", formatParams(model.A[:]), formatParams(model.B[:]))
+}
+
+func formatParams(params []float64) string {
+	s := make([]string, len(params))
+	for i, p := range params {
+		s[i] = fmt.Sprintf("%.0f", p)
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+// game is a finished game of a PGN file.
+type game struct {
+	fen   string
+	moves []string
+	// scores are the search scores of the moves in centipawns from the view of the moving side by the index of the move.
+	scores map[int]int16
+	// result is the result from the view of white.
+	result float64
+}
+
+var (
+	tagRegex        = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+	tokenRegex      = regexp.MustCompile(`\{[^}]*\}|;[^\n]*|[()]|[^\s(){};]+`)
+	scoreRegex      = regexp.MustCompile(`^\{\s*([+-]?\d+\.\d+)/\d+`)
+	mateRegex       = regexp.MustCompile(`^\{\s*([+-])M\d+/\d+`)
+	moveNumberRegex = regexp.MustCompile(`^\d+\.+`)
+	results         = map[string]float64{"1-0": 1, "0-1": 0, "1/2-1/2": 0.5}
+)
+
+// parsePGN parses all finished games of the PGN.
+// Unfinished games are skipped.
+func parsePGN(r io.Reader) ([]game, error) {
+	var games []game
+	var fen string
+	var movetext strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if groups := tagRegex.FindStringSubmatch(line); groups != nil {
+			if groups[1] == "FEN" {
+				fen = groups[2]
+			}
+			continue
+		}
+		movetext.WriteString(line)
+		movetext.WriteString("\n")
+
+		// The game is finished with the result
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		last := tokens[len(tokens)-1]
+		if _, ok := results[last]; !ok && last != "*" {
+			continue
+		}
+		if g, ok := parseMovetext(movetext.String()); ok {
+			g.fen = fen
+			games = append(games, g)
+		}
+		fen = ""
+		movetext.Reset()
+	}
+	return games, scanner.Err()
+}
+
+// parseMovetext parses the moves and the result of a game.
+// It returns false, if the game is not finished.
+func parseMovetext(movetext string) (game, bool) {
+	g := game{scores: map[int]int16{}}
+	variationDepth := 0
+	for _, token := range tokenRegex.FindAllString(movetext, -1) {
+		switch {
+		case token == "(":
+			variationDepth++
+		case token == ")":
+			variationDepth--
+		case strings.HasPrefix(token, "{") || strings.HasPrefix(token, ";"):
+			if variationDepth == 0 && len(g.moves) > 0 {
+				if score, ok := parseScore(token); ok {
+					g.scores[len(g.moves)-1] = score
+				}
+			}
+		case variationDepth > 0 || strings.HasPrefix(token, "$"):
+		case token == "*":
+			return g, false
+		default:
+			if result, ok := results[token]; ok {
+				g.result = result
+				return g, true
+			}
+			if token = moveNumberRegex.ReplaceAllString(token, ""); token != "" {
+				g.moves = append(g.moves, token)
+			}
+		}
+	}
+	return g, false
+}
+
+// parseScore returns the score of a comment in centipawns.
+// Mates are returned as checkmate values.
+func parseScore(comment string) (int16, bool) {
+	if groups := mateRegex.FindStringSubmatch(comment); groups != nil {
+		if groups[1] == "-" {
+			return -evaluation.INF, true
+		}
+		return evaluation.INF, true
+	}
+	groups := scoreRegex.FindStringSubmatch(comment)
+	if groups == nil {
+		return 0, false
+	}
+	pawns, err := strconv.ParseFloat(groups[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return int16(max(min(math.Round(100*pawns), float64(evaluation.INF)), -float64(evaluation.INF))), true
+}
+
+// samples replays the game and creates a sample for every quiet position after the skipped plies.
+// A position is quiet, if the side to move is not in check and the last move was not a capture.
+func (g game) samples(skip int) ([]evaluation.WDLSample, error) {
+	pos := position.New()
+	if g.fen != "" {
+		var err error
+		pos, err = position.NewFromFen(g.fen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var samples []evaluation.WDLSample
+	quiet := true
+	for i, san := range g.moves {
+		if i >= skip && quiet && !pos.IsInCheck(pos.SideToMove) {
+			result := g.result
+			if pos.SideToMove == types.BLACK {
+				result = 1 - result
+			}
+			score, found := g.scores[i]
+			if !found {
+				score = evaluation.Evaluation(pos)
+			}
+			if !evaluation.IsCheckmateValue(score) {
+				samples = append(samples, evaluation.NewWDLSample(pos, score, result))
+			}
+		}
+		m, err := pos.MoveFromSAN(san)
+		if err != nil {
+			return nil, err
+		}
+		quiet = !pos.IsCapture(m)
+		pos.MakeMove(m)
+	}
+	return samples, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPGN = `[Event "Test"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 {the italian bishop} Nc6 3. Qh5 Nf6?? (3... g6 4. Qf3) 4. Qxf7# 1-0
+
+[Event "Test"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"]
+[Result "1/2-1/2"]
+
+1... Kd7 2. e4 $1 Ke6 1/2-1/2
+
+[Event "Scores"]
+[Result "0-1"]
+
+1. f3 {+0.10/12 0.51s} e5 {-0.25/11} 2. g4 {-M1/3} Qh4# {+M1/1} 0-1
+
+[Event "Unfinished"]
+[Result "*"]
+
+1. d4 d5 *
+`
+
+func Test_parsePGN(t *testing.T) {
+	games, err := parsePGN(strings.NewReader(testPGN))
+	require.NoError(t, err)
+	require.Len(t, games, 3)
+
+	assert.Equal(t, "", games[0].fen)
+	assert.Equal(t, []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6??", "Qxf7#"}, games[0].moves)
+	assert.Equal(t, 1.0, games[0].result)
+
+	assert.Equal(t, "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", games[1].fen)
+	assert.Equal(t, []string{"Kd7", "e4", "Ke6"}, games[1].moves)
+	assert.Equal(t, 0.5, games[1].result)
+
+	assert.Equal(t, []string{"f3", "e5", "g4", "Qh4#"}, games[2].moves)
+	assert.Equal(t, map[int]int16{0: 10, 1: -25, 2: -evaluation.INF, 3: evaluation.INF}, games[2].scores)
+	assert.Equal(t, 0.0, games[2].result)
+}
+
+func Test_game_samples(t *testing.T) {
+	games, err := parsePGN(strings.NewReader(testPGN))
+	require.NoError(t, err)
+
+	samples, err := games[0].samples(0)
+	require.NoError(t, err)
+	// All positions before the moves are quiet
+	require.Len(t, samples, 7)
+	for i, s := range samples {
+		// Results from the view of the side to move
+		want := 1.0
+		if i%2 == 1 {
+			want = 0
+		}
+		assert.Equal(t, want, s.Result)
+		assert.Equal(t, i, s.Ply)
+	}
+
+	samples, err = games[0].samples(4)
+	require.NoError(t, err)
+	assert.Len(t, samples, 3)
+
+	// The search scores are used instead of the static evaluation and mates are skipped
+	samples, err = games[2].samples(0)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, int16(10), samples[0].Score)
+	assert.Equal(t, int16(-25), samples[1].Score)
+	assert.Equal(t, 1.0, samples[1].Result)
+
+	_, err = game{moves: []string{"e5"}}.samples(0)
+	assert.Error(t, err)
+}
@@ -13,3 +13,12 @@ func IsCheckmateValue(value int16) bool {
 	}
 	return false
 }
+
+// MateIn returns the number of moves until checkmate for a checkmate value seen from the root.
+// The number is positive, if the side to move mates, and negative, if the side to move gets mated.
+func MateIn(value int16) int {
+	if value > 0 {
+		return (int(INF-value) + 1) / 2
+	}
+	return -int(INF+value) / 2
+}
@@ -4,12 +4,22 @@ import (
 	"github.com/shaardie/clemens/pkg/position"
 )
 
+// DefaultContempt is the default value of the Contempt Factor.
+// It is that high, because we do not resign too early.
+const DefaultContempt int16 = 400
+
+var contempt = DefaultContempt
+
+// SetContempt sets the Contempt Factor. It must not be called while a search is running.
+func SetContempt(c int16) {
+	contempt = c
+}
+
 // Calculates the Contempt Factor for drawish positions, see https://www.chessprogramming.org/Contempt_Factor
 func Contempt(pos *position.Position) int16 {
 	if IsEndgame(pos) {
 		return 0
 	}
 
-	// We do not resign too early
-	return 400
+	return contempt
 }
@@ -22,15 +22,15 @@ const (
 	endgameBorder = maxGamePhase / 2
 )
 
-// Evaluation evaluates the position.
+// Evaluation evaluates the position using the default cache.
 func Evaluation(pos *position.Position) int16 {
-	return evalWithCache(pos)
+	return DefaultCache.Evaluation(pos)
 }
 
-// evalWithCache uses a transposition table to lookup, if a position was already evaluted.
-// If so, it returns the cached value other calls the actual evaluation and safe the result in the cache.
-func evalWithCache(pos *position.Position) int16 {
-	score, found := tTable.get(pos.ZobristHash)
+// Evaluation evaluates the position and uses the cache to lookup, if the position was already evaluated.
+// If so, it returns the cached value, otherwise it calls the actual evaluation and saves the result in the cache.
+func (c *Cache) Evaluation(pos *position.Position) int16 {
+	score, found := c.get(pos.ZobristHash)
 	if found {
 		return score
 	}
@@ -39,10 +39,9 @@ func evalWithCache(pos *position.Position) int16 {
 	score = e.do(pos)
 
 	// Save score in transposition table
-	tTable.save(pos.ZobristHash, score)
+	c.save(pos.ZobristHash, score)
 
 	return score
-
 }
 
 type eval struct {
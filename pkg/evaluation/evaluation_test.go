@@ -32,3 +32,32 @@ func TestPosition_Evaluation(t *testing.T) {
 		})
 	}
 }
+
+func TestMateIn(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int16
+		want  int
+	}{
+		{
+			name:  "mate in 1",
+			value: INF - 1,
+			want:  1,
+		},
+		{
+			name:  "mate in 2",
+			value: INF - 3,
+			want:  2,
+		},
+		{
+			name:  "mated in 1",
+			value: -INF + 2,
+			want:  -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MateIn(tt.value))
+		})
+	}
+}
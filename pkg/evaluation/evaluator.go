@@ -0,0 +1,55 @@
+package evaluation
+
+import (
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// phases contains the parts of the evaluators, which only depend on the material on the board.
+type phases struct{}
+
+func (phases) PieceValue(pt types.PieceType) int16 {
+	return PieceValue[pt]
+}
+
+func (phases) IsEndgame(pos *position.Position) bool {
+	return IsEndgame(pos)
+}
+
+func (phases) IsPawnEndgame(pos *position.Position) bool {
+	return IsPawnEndgame(pos)
+}
+
+// HandCrafted is the hand-crafted evaluation of the engine.
+type HandCrafted struct {
+	phases
+	cache *Cache
+}
+
+// NewHandCrafted creates the hand-crafted evaluation using the cache.
+// The cache might be nil, in which case every position is evaluated again.
+func NewHandCrafted(cache *Cache) HandCrafted {
+	return HandCrafted{cache: cache}
+}
+
+func (h HandCrafted) Evaluate(pos *position.Position) int16 {
+	if h.cache == nil {
+		e := eval{}
+		return e.do(pos)
+	}
+	return h.cache.Evaluation(pos)
+}
+
+// Material only counts the material on the board, e.g. to test the search.
+type Material struct {
+	phases
+}
+
+func (Material) Evaluate(pos *position.Position) int16 {
+	e := eval{}
+	e.evalBaseMaterial(pos)
+	if pos.SideToMove == types.BLACK {
+		return -e.baseScore
+	}
+	return e.baseScore
+}
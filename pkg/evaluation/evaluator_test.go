@@ -0,0 +1,51 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterial_Evaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int16
+	}{
+		{
+			name: "start position",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			want: 0,
+		},
+		{
+			name: "white is a queen up",
+			fen:  "rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			want: PieceValue[types.QUEEN],
+		},
+		{
+			name: "from the view of black",
+			fen:  "rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1",
+			want: -PieceValue[types.QUEEN],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, Material{}.Evaluate(pos))
+		})
+	}
+}
+
+func TestHandCrafted_Evaluate(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	withoutCache := NewHandCrafted(nil).Evaluate(pos)
+	withCache := NewHandCrafted(NewCache(1))
+	// Evaluate twice to use the cache
+	assert.Equal(t, withoutCache, withCache.Evaluate(pos))
+	assert.Equal(t, withoutCache, withCache.Evaluate(pos))
+}
@@ -69,6 +69,85 @@ func StaticExchangeEvaluation(pos *position.Position, m *move.Move) int16 {
 	return gain[0]
 }
 
+// StaticExchangeEvaluationAtLeast returns true, if the static exchange evaluation of the move is at least the threshold.
+// In contrast to StaticExchangeEvaluation, it stops as soon as the result is known, e.g. to separate good and bad captures.
+// The exchange is not calculated for promotions, en passants and castlings, so they only depend on the threshold.
+// https://www.chessprogramming.org/SEE_-_The_Swap_Algorithm
+func StaticExchangeEvaluationAtLeast(pos *position.Position, m *move.Move, threshold int16) bool {
+	if m.GetMoveType() != move.NORMAL {
+		return threshold <= 0
+	}
+
+	sourceSquare := m.GetSourceSquare()
+	targetSquare := m.GetTargetSquare()
+
+	// The gain of the move, if the piece is not recaptured
+	swap := -threshold
+	if target := pos.PiecesBoard[targetSquare]; target != types.NO_PIECE {
+		swap += PieceValue[target.Type()]
+	}
+	if swap < 0 {
+		return false
+	}
+
+	// The gain of the move, if the piece is recaptured without any further exchange
+	swap = PieceValue[pos.PiecesBoard[sourceSquare].Type()] - swap
+	if swap <= 0 {
+		return true
+	}
+
+	diagonalSlider := pos.PiecesBitboard[types.WHITE][types.BISHOP] | pos.PiecesBitboard[types.BLACK][types.BISHOP] |
+		pos.PiecesBitboard[types.WHITE][types.QUEEN] | pos.PiecesBitboard[types.BLACK][types.QUEEN]
+	verticalAndHorizonalSlider := pos.PiecesBitboard[types.WHITE][types.ROOK] | pos.PiecesBitboard[types.BLACK][types.ROOK] |
+		pos.PiecesBitboard[types.WHITE][types.QUEEN] | pos.PiecesBitboard[types.BLACK][types.QUEEN]
+
+	occupied := pos.AllPieces ^ bitboard.BitBySquares(sourceSquare) ^ bitboard.BitBySquares(targetSquare)
+	attacks := pos.SquareAttackedBy(targetSquare) |
+		bishop.AttacksBySquare(targetSquare, occupied)&diagonalSlider |
+		rook.AttacksBySquare(targetSquare, occupied)&verticalAndHorizonalSlider
+	sideToMove := pos.SideToMove
+
+	// result is 1, if the side to move of the position wins the exchange, and is flipped with every capture
+	var result int16 = 1
+	for {
+		sideToMove = types.SwitchColor(sideToMove)
+		attacks &= occupied
+		ownAttacks := attacks & pos.AllPiecesByColor[sideToMove]
+		if ownAttacks == bitboard.Empty {
+			break
+		}
+		result ^= 1
+
+		var attackerType types.PieceType
+		attacker := getLeastValuablePiece(pos, ownAttacks, sideToMove, &attackerType)
+
+		// Capturing with the king is only possible, if the opponent has no attacks left
+		if attackerType == types.KING {
+			if attacks&^pos.AllPiecesByColor[sideToMove] != bitboard.Empty {
+				result ^= 1
+			}
+			break
+		}
+
+		swap = PieceValue[attackerType] - swap
+		if swap < result {
+			break
+		}
+
+		occupied ^= attacker
+		switch attackerType {
+		case types.PAWN, types.BISHOP:
+			attacks |= bishop.AttacksBySquare(targetSquare, occupied) & diagonalSlider
+		case types.ROOK:
+			attacks |= rook.AttacksBySquare(targetSquare, occupied) & verticalAndHorizonalSlider
+		case types.QUEEN:
+			attacks |= bishop.AttacksBySquare(targetSquare, occupied)&diagonalSlider |
+				rook.AttacksBySquare(targetSquare, occupied)&verticalAndHorizonalSlider
+		}
+	}
+	return result == 1
+}
+
 func considerXrays(pos *position.Position, square uint8, occupied, alreadyAttacked bitboard.Bitboard) bitboard.Bitboard {
 	attacks := bitboard.Empty
 
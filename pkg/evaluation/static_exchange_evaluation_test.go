@@ -44,3 +44,67 @@ func TestStaticExchangeEvaluation(t *testing.T) {
 		})
 	}
 }
+
+func TestStaticExchangeEvaluationAtLeast(t *testing.T) {
+	tests := []struct {
+		name         string
+		fen          string
+		sourceSquare uint8
+		targetSquare uint8
+		value        int16
+	}{
+		{
+			name:         "position 1",
+			fen:          "1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1",
+			sourceSquare: types.SQUARE_E1,
+			targetSquare: types.SQUARE_E5,
+			value:        100,
+		},
+		{
+			name:         "position 2",
+			fen:          "1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1",
+			sourceSquare: types.SQUARE_D3,
+			targetSquare: types.SQUARE_E5,
+			value:        -210,
+		},
+		{
+			name:         "recapture of the queen",
+			fen:          "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			sourceSquare: types.SQUARE_F3,
+			targetSquare: types.SQUARE_H3,
+			value:        -300,
+		},
+		{
+			name:         "undefended pawn",
+			fen:          "4k3/8/8/3p4/8/8/8/3QK3 w - - 0 1",
+			sourceSquare: types.SQUARE_D1,
+			targetSquare: types.SQUARE_D5,
+			value:        100,
+		},
+		{
+			name:         "quiet move",
+			fen:          "4k3/8/8/3p4/8/8/8/3QK3 w - - 0 1",
+			sourceSquare: types.SQUARE_D1,
+			targetSquare: types.SQUARE_D4,
+			value:        0,
+		},
+		{
+			name:         "quiet move to an attacked square",
+			fen:          "4k3/8/8/3p4/8/8/8/2Q1K3 w - - 0 1",
+			sourceSquare: types.SQUARE_C1,
+			targetSquare: types.SQUARE_C4,
+			value:        -910,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			assert.NoError(t, err)
+			var m move.Move
+			m.SetSourceSquare(tt.sourceSquare)
+			m.SetTargetSquare(tt.targetSquare)
+			assert.True(t, StaticExchangeEvaluationAtLeast(pos, &m, tt.value))
+			assert.False(t, StaticExchangeEvaluationAtLeast(pos, &m, tt.value+1))
+		})
+	}
+}
@@ -1,43 +1,66 @@
 package evaluation
 
 import (
+	"sync/atomic"
 	"unsafe"
 )
 
+// transpositionEntry holds the upper 48 bits of the zobrist hash and the score in the lower 16 bits.
+// Packing both into a single word makes the cache safe to be used by multiple search threads at once.
 type transpositionEntry struct {
-	zobristHash uint64
-	score       int16
+	keyAndScore atomic.Uint64
 }
 
-// 32MB
-const transpositionTableSizeinMB = 1024 * 1024 * 32
+const transpositionKeyMask uint64 = 0xFFFFFFFFFFFF0000
 
-var transpositionTableSize uint64
-var tTable transpositionTable
+// DefaultCacheSizeInMB is the size of the evaluation cache, if not resized.
+const DefaultCacheSizeInMB = 32
 
-type transpositionTable []transpositionEntry
+// Cache caches the evaluations of positions and is shared by all threads of a search.
+// Independent searches, e.g. different analyses in the same process, should use their own caches.
+type Cache struct {
+	entries []transpositionEntry
+	size    uint64
+}
+
+// DefaultCache is the cache for all evaluations, which do not need their own, e.g. the command line interface.
+var DefaultCache = NewCache(DefaultCacheSizeInMB)
 
-func init() {
-	reset()
+// NewCache creates an empty evaluation cache of the size in MB.
+func NewCache(sizeInMB int) *Cache {
+	c := &Cache{}
+	c.Resize(sizeInMB)
+	return c
+}
+
+// Resize replaces the evaluation cache with an empty one of the size in MB, if the size changes.
+// It must not be called while a search is running.
+func (c *Cache) Resize(sizeInMB int) {
+	n := max(uint64(sizeInMB)*1024*1024/uint64(unsafe.Sizeof(transpositionEntry{})), 1)
+	if n == c.size {
+		return
+	}
+	c.size = n
+	// Drop the old cache first, so the garbage collector is able to free it for the new one.
+	c.entries = nil
+	c.entries = make([]transpositionEntry, c.size)
 }
 
-func reset() {
-	transpositionTableSize = uint64(transpositionTableSizeinMB / unsafe.Sizeof(transpositionEntry{}))
-	tTable = make([]transpositionEntry, transpositionTableSize)
+// Clear clears the evaluation cache. It must not be called while a search is running.
+func (c *Cache) Clear() {
+	clear(c.entries)
 }
 
-func (tt transpositionTable) get(zobristHash uint64) (int16, bool) {
-	key := zobristHash % transpositionTableSize
-	te := tt[key]
-	if tt[key].zobristHash != zobristHash {
-		return tt[key].score, false
+func (c *Cache) get(zobristHash uint64) (int16, bool) {
+	keyAndScore := c.entries[zobristHash%c.size].keyAndScore.Load()
+	score := int16(keyAndScore)
+	if keyAndScore&transpositionKeyMask != zobristHash&transpositionKeyMask {
+		return score, false
 	}
-	return te.score, true
+	return score, true
 }
 
 // save save the new transposition entry.
-func (tt transpositionTable) save(zobristHash uint64, score int16) {
-	key := zobristHash % transpositionTableSize
-	tt[key].zobristHash = zobristHash
-	tt[key].score = score
+func (c *Cache) save(zobristHash uint64, score int16) {
+	c.entries[zobristHash%c.size].keyAndScore.Store(zobristHash&transpositionKeyMask | uint64(uint16(score)))
 }
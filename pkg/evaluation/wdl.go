@@ -0,0 +1,149 @@
+package evaluation
+
+import (
+	"math"
+
+	"github.com/shaardie/clemens/pkg/position"
+)
+
+// Win/Draw/Loss model, see https://www.chessprogramming.org/Pawn_Advantage,_Win_Percentage,_and_Elo
+// The probability to win is a logistic function of the score,
+// 	win(score) = 1 / (1 + exp((a - score) / b)),
+// the probability to lose is the probability to win for the negated score and the rest is the probability of a draw.
+// The parameters a and b are linear functions of the game phase and the game ply,
+// since the same score means something different in the opening than in the endgame.
+
+const (
+	wdlFeatures = 3
+	// wdlMaxPly is the game ply, after which the ply does not change the model anymore.
+	wdlMaxPly = 240
+	// wdlMinB is the minimal spread of the logistic function.
+	wdlMinB = 1
+)
+
+// WDLModel contains the parameters of the Win/Draw/Loss model.
+// A and B are the weights of the features constant, game phase and game ply.
+type WDLModel struct {
+	A [wdlFeatures]float64
+	B [wdlFeatures]float64
+}
+
+// DefaultWDLModel is the model used for the UCI option `UCI_ShowWDL`.
+// It is fitted from the search scores of about 2000 games of clemens against itself
+// with 5000 nodes per move and 8 random plies at the start,
+//
+//	go run ./cmd/selfplay -games 2000 -nodes 5000 -random 8 -seed 1 -pgn selfplay.pgn
+//	go run ./cmd/wdlfit -pgn selfplay.pgn
+//
+// and has to be refitted the same way, if the evaluation changes.
+var DefaultWDLModel = WDLModel{
+	A: [wdlFeatures]float64{-30, 97, 432},
+	B: [wdlFeatures]float64{28, 120, 160},
+}
+
+// WDLSample is a single position with its score and the result of the game from the view of the side to move.
+// The result is 1 for a win, 0.5 for a draw and 0 for a loss.
+type WDLSample struct {
+	Score  int16
+	Phase  int16
+	Ply    int
+	Result float64
+}
+
+// NewWDLSample creates a sample for the position.
+func NewWDLSample(pos *position.Position, score int16, result float64) WDLSample {
+	return WDLSample{
+		Score:  score,
+		Phase:  gamePhase(pos),
+		Ply:    int(pos.Ply),
+		Result: result,
+	}
+}
+
+// WDL returns the probabilities to win, to draw and to lose in per mille for the score of the side to move.
+func WDL(pos *position.Position, score int16) (win, draw, loss int) {
+	return DefaultWDLModel.WDL(pos, score)
+}
+
+// WDL returns the probabilities to win, to draw and to lose in per mille for the score of the side to move.
+func (m WDLModel) WDL(pos *position.Position, score int16) (win, draw, loss int) {
+	if IsCheckmateValue(score) {
+		if score > 0 {
+			return 1000, 0, 0
+		}
+		return 0, 0, 1000
+	}
+	w, _, l := m.probabilities(float64(score), gamePhase(pos), int(pos.Ply))
+	win = int(math.Round(1000 * w))
+	loss = int(math.Round(1000 * l))
+	return win, 1000 - win - loss, loss
+}
+
+// probabilities returns the probabilities to win, to draw and to lose.
+func (m WDLModel) probabilities(score float64, phase int16, ply int) (win, draw, loss float64) {
+	features := [wdlFeatures]float64{
+		1,
+		float64(phase) / maxGamePhase,
+		float64(min(ply, wdlMaxPly)) / wdlMaxPly,
+	}
+	var a, b float64
+	for i, f := range features {
+		a += m.A[i] * f
+		b += m.B[i] * f
+	}
+	b = max(b, wdlMinB)
+	win = 1 / (1 + math.Exp((a-score)/b))
+	loss = 1 / (1 + math.Exp((a+score)/b))
+	return win, max(1-win-loss, 0), loss
+}
+
+// loss returns the mean negative log-likelihood of the model for the samples.
+func (m WDLModel) loss(samples []WDLSample) float64 {
+	const epsilon = 1e-9
+	var sum float64
+	for _, s := range samples {
+		win, draw, loss := m.probabilities(float64(s.Score), s.Phase, s.Ply)
+		p := draw
+		if s.Result > 0.5 {
+			p = win
+		} else if s.Result < 0.5 {
+			p = loss
+		}
+		sum -= math.Log(max(p, epsilon))
+	}
+	return sum / float64(len(samples))
+}
+
+// FitWDLModel fits the model to the samples by maximizing the likelihood of the results.
+// It uses a simple local search similar to the Texel Tuning starting at the given model,
+// see https://www.chessprogramming.org/Texel%27s_Tuning_Method
+func FitWDLModel(samples []WDLSample, model WDLModel, iterations int) WDLModel {
+	if len(samples) == 0 {
+		return model
+	}
+	step := 16.0
+	best := model.loss(samples)
+	for range iterations {
+		improved := false
+		for _, params := range []*[wdlFeatures]float64{&model.A, &model.B} {
+			for i := range params {
+				for _, delta := range []float64{step, -step} {
+					params[i] += delta
+					if l := model.loss(samples); l < best {
+						best = l
+						improved = true
+						break
+					}
+					params[i] -= delta
+				}
+			}
+		}
+		if !improved {
+			step /= 2
+			if step < 0.1 {
+				break
+			}
+		}
+	}
+	return model
+}
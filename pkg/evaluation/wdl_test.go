@@ -0,0 +1,130 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWDL(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		score    int16
+		wantWin  int
+		wantDraw int
+		wantLoss int
+	}{
+		{
+			name:     "equal",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			score:    0,
+			wantWin:  389,
+			wantDraw: 222,
+			wantLoss: 389,
+		},
+		{
+			name:     "winning in the opening",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			score:    400,
+			wantWin:  905,
+			wantDraw: 54,
+			wantLoss: 41,
+		},
+		{
+			name:     "endgame",
+			fen:      "4k3/4p3/8/8/8/8/4P3/4K3 w - - 0 60",
+			score:    400,
+			wantWin:  885,
+			wantDraw: 111,
+			wantLoss: 4,
+		},
+		{
+			name:     "losing",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			score:    -300,
+			wantWin:  77,
+			wantDraw: 95,
+			wantLoss: 828,
+		},
+		{
+			name:     "mate",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			score:    INF - 3,
+			wantWin:  1000,
+			wantDraw: 0,
+			wantLoss: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			win, draw, loss := WDL(pos, tt.score)
+			assert.Equal(t, tt.wantWin, win)
+			assert.Equal(t, tt.wantDraw, draw)
+			assert.Equal(t, tt.wantLoss, loss)
+		})
+	}
+}
+
+func TestWDL_ply(t *testing.T) {
+	// The positions only differ by the game ply
+	early, err := position.NewFromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	require.NoError(t, err)
+	late, err := position.NewFromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 61")
+	require.NoError(t, err)
+
+	// The same score is less decisive later in the game
+	earlyWin, earlyDraw, earlyLoss := WDL(early, 200)
+	lateWin, lateDraw, lateLoss := WDL(late, 200)
+	assert.Greater(t, earlyWin, lateWin)
+	assert.Less(t, earlyDraw, lateDraw)
+	assert.NotEqual(t, earlyLoss, lateLoss)
+
+	// The ply does not change the model after wdlMaxPly, even beyond the range of a byte
+	veryLate, err := position.NewFromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 200")
+	require.NoError(t, err)
+	later, err := position.NewFromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 130")
+	require.NoError(t, err)
+	veryLateWin, veryLateDraw, veryLateLoss := WDL(veryLate, 200)
+	laterWin, laterDraw, laterLoss := WDL(later, 200)
+	assert.Less(t, laterWin, lateWin)
+	assert.Equal(t, laterWin, veryLateWin)
+	assert.Equal(t, laterDraw, veryLateDraw)
+	assert.Equal(t, laterLoss, veryLateLoss)
+}
+
+func TestFitWDLModel(t *testing.T) {
+	want := WDLModel{
+		A: [wdlFeatures]float64{100, 200, 50},
+		B: [wdlFeatures]float64{50, 30, 20},
+	}
+
+	// Create samples with the exact frequencies of the model
+	var samples []WDLSample
+	for phase := int16(0); phase <= maxGamePhase; phase += 8 {
+		for ply := 0; ply <= wdlMaxPly; ply += 120 {
+			for score := int16(-500); score <= 500; score += 50 {
+				win, draw, loss := want.probabilities(float64(score), phase, ply)
+				for _, r := range []struct {
+					result float64
+					p      float64
+				}{{1, win}, {0.5, draw}, {0, loss}} {
+					for range int(100 * r.p) {
+						samples = append(samples, WDLSample{Score: score, Phase: phase, Ply: ply, Result: r.result})
+					}
+				}
+			}
+		}
+	}
+
+	got := FitWDLModel(samples, DefaultWDLModel, 1000)
+	assert.Less(t, got.loss(samples), DefaultWDLModel.loss(samples))
+	for i := range wdlFeatures {
+		assert.InDelta(t, want.A[i], got.A[i], 20)
+		assert.InDelta(t, want.B[i], got.B[i], 20)
+	}
+}
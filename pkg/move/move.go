@@ -84,3 +84,8 @@ func (m *Move) GetScore() uint16 {
 func (m *Move) SetScore(s uint16) {
 	*m |= Move(s) << 16
 }
+
+// WithoutScore returns the move without the score, so it can be compared to moves from other move lists.
+func (m Move) WithoutScore() Move {
+	return m & 0xFFFF
+}
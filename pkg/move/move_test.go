@@ -19,4 +19,9 @@ func TestMove(t *testing.T) {
 	assert.Equal(t, PROMOTION, m.GetMoveType())
 	assert.Equal(t, types.ROOK, m.GetPromitionPieceType())
 	assert.Equal(t, uint16(1234), m.GetScore())
+
+	withoutScore := m.WithoutScore()
+	assert.Equal(t, uint16(0), withoutScore.GetScore())
+	assert.Equal(t, types.SQUARE_H7, withoutScore.GetSourceSquare())
+	assert.Equal(t, types.ROOK, withoutScore.GetPromitionPieceType())
 }
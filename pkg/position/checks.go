@@ -2,6 +2,12 @@ package position
 
 import (
 	"github.com/shaardie/clemens/pkg/bitboard"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/pieces/bishop"
+	"github.com/shaardie/clemens/pkg/pieces/knight"
+	"github.com/shaardie/clemens/pkg/pieces/pawn"
+	"github.com/shaardie/clemens/pkg/pieces/queen"
+	"github.com/shaardie/clemens/pkg/pieces/rook"
 	"github.com/shaardie/clemens/pkg/types"
 )
 
@@ -11,3 +17,49 @@ func (pos *Position) IsInCheck(c types.Color) bool {
 	filtered := attacks & pos.AllPiecesByColor[types.SwitchColor(c)]
 	return filtered != 0
 }
+
+// GivesCheck returns true, if the pseudo legal move of the side to move checks the king of the opponent.
+// The move is not made, except for the rare castling and en passant moves, which move or remove a second piece.
+func (pos *Position) GivesCheck(m move.Move) bool {
+	moveType := m.GetMoveType()
+	if moveType == move.CASTLING || moveType == move.EN_PASSANT {
+		next := *pos
+		next.MakeMove(m)
+		return next.IsInCheck(next.SideToMove)
+	}
+
+	us := pos.SideToMove
+	king := pos.PiecesBitboard[types.SwitchColor(us)][types.KING]
+	kingSquare := bitboard.LeastSignificantOneBit(king)
+	source, target := m.GetSourceSquare(), m.GetTargetSquare()
+	occupied := pos.AllPieces&^bitboard.BitBySquares(source) | bitboard.BitBySquares(target)
+
+	// Direct check by the moved piece
+	pt := pos.PiecesBoard[source].Type()
+	if moveType == move.PROMOTION {
+		pt = m.GetPromitionPieceType()
+	}
+	var attacks bitboard.Bitboard
+	switch pt {
+	case types.PAWN:
+		attacks = pawn.AttacksBySquare(us, target)
+	case types.KNIGHT:
+		attacks = knight.AttacksBySquare(target)
+	case types.BISHOP:
+		attacks = bishop.AttacksBySquare(target, occupied)
+	case types.ROOK:
+		attacks = rook.AttacksBySquare(target, occupied)
+	case types.QUEEN:
+		attacks = queen.AttacksBySquare(target, occupied)
+	}
+	if attacks&king != 0 {
+		return true
+	}
+
+	// Discovered check by a slider, which was blocked by the moved piece
+	moved := bitboard.BitBySquares(source)
+	diagonalSlider := (pos.PiecesBitboard[us][types.BISHOP] | pos.PiecesBitboard[us][types.QUEEN]) &^ moved
+	straightSlider := (pos.PiecesBitboard[us][types.ROOK] | pos.PiecesBitboard[us][types.QUEEN]) &^ moved
+	return bishop.AttacksBySquare(kingSquare, occupied)&diagonalSlider != 0 ||
+		rook.AttacksBySquare(kingSquare, occupied)&straightSlider != 0
+}
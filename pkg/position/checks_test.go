@@ -3,6 +3,7 @@ package position
 import (
 	"testing"
 
+	"github.com/shaardie/clemens/pkg/move"
 	"github.com/shaardie/clemens/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,3 +37,38 @@ func TestPosition_IsCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestPosition_GivesCheck(t *testing.T) {
+	fens := []string{
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+		// Discovered checks, promotions and castling with check
+		"3k4/2N2P2/8/B7/8/8/8/R3K3 w Q - 0 1",
+		// En passant with a discovered check
+		"8/8/8/1k1pP2R/8/8/8/4K3 w - d6 0 1",
+		"4k3/8/8/8/K1Pp3r/8/8/8 b - c3 0 1",
+		// Checks by pawns and knights
+		"8/8/3k4/8/2P5/4N3/8/4K3 w - - 0 1",
+	}
+	for _, fen := range fens {
+		t.Run(fen, func(t *testing.T) {
+			pos, err := NewFromFen(fen)
+			assert.NoError(t, err)
+			moves := move.NewMoveList()
+			pos.GeneratePseudoLegalMoves(moves)
+			checks := 0
+			for i := range moves.Length() {
+				m := *moves.Get(i)
+				next := *pos
+				next.MakeMove(m)
+				if !next.IsLegal() {
+					continue
+				}
+				assert.Equal(t, next.IsInCheck(next.SideToMove), pos.GivesCheck(m), m.String())
+				if pos.GivesCheck(m) {
+					checks++
+				}
+			}
+			assert.Positive(t, checks)
+		})
+	}
+}
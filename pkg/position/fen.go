@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"unicode"
@@ -51,7 +52,8 @@ func NewFromFen(fen string) (*Position, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to set number of full moves fen token, %w", err)
 	}
-	pos.Ply = uint8(2*numberOfFullMoves - 1)
+	// The ply saturates instead of wrapping around for invalid move numbers.
+	pos.Ply = uint16(min(max(2*numberOfFullMoves-1, 1), math.MaxUint16))
 	if pos.SideToMove == types.WHITE {
 		pos.Ply--
 	}
@@ -1,6 +1,7 @@
 package position
 
 import (
+	"math"
 	"reflect"
 	"testing"
 
@@ -222,3 +223,29 @@ func TestPosition_ToFen(t *testing.T) {
 		New().ToFen(),
 	)
 }
+
+func TestNewFromFen_ply(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want uint16
+	}{
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", want: 0},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", want: 1},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 200", want: 398},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 200", want: 399},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 0", want: 0},
+		{fen: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 100000", want: math.MaxUint16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			pos, err := NewFromFen(tt.fen)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, pos.Ply)
+		})
+	}
+
+	// The move number survives long games
+	pos, err := NewFromFen("4k3/8/8/8/8/8/4P3/4K3 b - - 0 200")
+	assert.NoError(t, err)
+	assert.Equal(t, "4k3/8/8/8/8/8/4P3/4K3 b - - 0 200", pos.ToFen())
+}
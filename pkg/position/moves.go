@@ -97,6 +97,23 @@ func (pos *Position) GeneratePseudoLegalCaptures(moves *move.MoveList) {
 	)
 }
 
+// LegalMoves returns all legal moves of the position.
+func (pos *Position) LegalMoves() []move.Move {
+	moves := move.NewMoveList()
+	pos.GeneratePseudoLegalMoves(moves)
+	legal := make([]move.Move, 0, moves.Length())
+	for i := range moves.Length() {
+		m := *moves.Get(i)
+		prevPos := *pos
+		pos.MakeMove(m)
+		if pos.IsLegal() {
+			legal = append(legal, m)
+		}
+		*pos = prevPos
+	}
+	return legal
+}
+
 // GeneratePseudoLegalMoves generates all pseudo legal moves
 func (pos *Position) GeneratePseudoLegalMoves(moves *move.MoveList) {
 	occupied := pos.AllPieces
@@ -365,21 +382,33 @@ func generateMovesHelper(moves *move.MoveList, sources, occupied, destinations b
 	}
 }
 
+// MakeMoveFromString makes the move given in the long algebraic notation of the UCI protocol, e.g. e2e4 or e7e8q.
 func (pos *Position) MakeMoveFromString(s string) error {
+	m, err := pos.MoveFromString(s)
+	if err != nil {
+		return err
+	}
+	pos.MakeMove(m)
+	return nil
+}
+
+// MoveFromString returns the move given in the long algebraic notation of the UCI protocol for this position.
+// The move is not checked for legality.
+func (pos *Position) MoveFromString(s string) (move.Move, error) {
 	var m move.Move
 	if len(s) < 4 {
-		return errors.New("input to small")
+		return move.NullMove, errors.New("input to small")
 	}
 
 	sourceSquare, err := types.SquareFromString(s[0:2])
 	if err != nil {
-		return err
+		return move.NullMove, err
 	}
 	m.SetSourceSquare(sourceSquare)
 
 	destinationSquare, err := types.SquareFromString(s[2:4])
 	if err != nil {
-		return err
+		return move.NullMove, err
 	}
 	m.SetTargetSquare(destinationSquare)
 
@@ -393,14 +422,13 @@ func (pos *Position) MakeMoveFromString(s string) error {
 			m.SetMoveType(move.PROMOTION)
 			pt, err := types.PieceTypeFromString(string(s[4]))
 			if err != nil {
-				return err
+				return move.NullMove, err
 			}
 			m.SetPromitionPieceType(pt)
 		}
 	}
 
-	pos.MakeMove(m)
-	return nil
+	return m, nil
 }
 
 func pawnMoveWithPromotion(moves *move.MoveList, sideToMove types.Color, sourceSquare, targetSquare uint8) {
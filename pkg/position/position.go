@@ -25,7 +25,8 @@ type Position struct {
 	// En passant square
 	EnPassant     uint8
 	HalfMoveClock uint8
-	Ply           uint8
+	// Ply is the number of plies since the start of the game.
+	Ply uint16
 }
 
 func New() *Position {
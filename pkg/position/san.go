@@ -0,0 +1,153 @@
+package position
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// sanRegex matches a move in the Standard Algebraic Notation without castling, checks and annotations.
+// The groups are the piece, the source file, the source rank, the target square and the promotion piece.
+var sanRegex = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?x?([a-h][1-8])(?:=?([NBRQ]))?$`)
+
+// MoveFromSAN returns the legal move given in the Standard Algebraic Notation for this position, e.g. Nf3, exd5 or O-O.
+// See https://www.chessprogramming.org/Algebraic_Chess_Notation#Standard_Algebraic_Notation_.28SAN.29
+func (pos *Position) MoveFromSAN(san string) (move.Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.ReplaceAll(s, "0", "O")
+
+	var match func(m move.Move) bool
+	switch s {
+	case "O-O", "O-O-O":
+		targetFile := types.FILE_G
+		if s == "O-O-O" {
+			targetFile = types.FILE_C
+		}
+		match = func(m move.Move) bool {
+			return m.GetMoveType() == move.CASTLING && types.FileOfSquare(m.GetTargetSquare()) == targetFile
+		}
+	default:
+		groups := sanRegex.FindStringSubmatch(s)
+		if groups == nil {
+			return move.NullMove, fmt.Errorf("invalid move %v", san)
+		}
+		pt := types.PAWN
+		if groups[1] != "" {
+			pt, _ = types.PieceTypeFromString(strings.ToLower(groups[1]))
+			if groups[1] == "K" {
+				pt = types.KING
+			}
+		}
+		target, err := types.SquareFromString(groups[4])
+		if err != nil {
+			return move.NullMove, err
+		}
+		match = func(m move.Move) bool {
+			source := m.GetSourceSquare()
+			if pos.GetPiece(source).Type() != pt || m.GetTargetSquare() != target || m.GetMoveType() == move.CASTLING {
+				return false
+			}
+			if groups[2] != "" && types.FileOfSquare(source) != groups[2][0]-'a' {
+				return false
+			}
+			if groups[3] != "" && types.RankOfSquare(source) != groups[3][0]-'1' {
+				return false
+			}
+			if groups[5] == "" {
+				return m.GetMoveType() != move.PROMOTION
+			}
+			promotion, _ := types.PieceTypeFromString(strings.ToLower(groups[5]))
+			return m.GetMoveType() == move.PROMOTION && m.GetPromitionPieceType() == promotion
+		}
+	}
+
+	found := move.NullMove
+	moves := move.NewMoveList()
+	pos.GeneratePseudoLegalMoves(moves)
+	for i := range moves.Length() {
+		m := *moves.Get(i)
+		if !match(m) {
+			continue
+		}
+		prevPos := *pos
+		pos.MakeMove(m)
+		legal := pos.IsLegal()
+		*pos = prevPos
+		if !legal {
+			continue
+		}
+		if found != move.NullMove {
+			return move.NullMove, fmt.Errorf("ambiguous move %v", san)
+		}
+		found = m
+	}
+	if found == move.NullMove {
+		return move.NullMove, fmt.Errorf("illegal move %v", san)
+	}
+	return found, nil
+}
+
+// SAN returns the legal move in the Standard Algebraic Notation for this position including the suffix for a check or a mate.
+func (pos *Position) SAN(m move.Move) string {
+	var sb strings.Builder
+	source, target := m.GetSourceSquare(), m.GetTargetSquare()
+	pt := pos.GetPiece(source).Type()
+	switch {
+	case m.GetMoveType() == move.CASTLING:
+		if types.FileOfSquare(target) == types.FILE_G {
+			sb.WriteString("O-O")
+		} else {
+			sb.WriteString("O-O-O")
+		}
+	case pt == types.PAWN:
+		if pos.IsCapture(m) {
+			sb.WriteByte('a' + types.FileOfSquare(source))
+			sb.WriteByte('x')
+		}
+		sb.WriteString(types.SquareToString(target))
+		if m.GetMoveType() == move.PROMOTION {
+			sb.WriteByte('=')
+			sb.WriteString(strings.ToUpper(m.GetPromitionPieceType().String()))
+		}
+	default:
+		sb.WriteByte(sanPieces[pt])
+		// The source square is added as far as needed to distinguish the move from the ones of the other pieces of the same type.
+		var ambiguous, sameFile, sameRank bool
+		for _, other := range pos.LegalMoves() {
+			otherSource := other.GetSourceSquare()
+			if other.GetTargetSquare() != target || otherSource == source || pos.GetPiece(otherSource).Type() != pt {
+				continue
+			}
+			ambiguous = true
+			sameFile = sameFile || types.FileOfSquare(otherSource) == types.FileOfSquare(source)
+			sameRank = sameRank || types.RankOfSquare(otherSource) == types.RankOfSquare(source)
+		}
+		if ambiguous && (!sameFile || sameRank) {
+			sb.WriteByte('a' + types.FileOfSquare(source))
+		}
+		if sameFile {
+			sb.WriteByte('1' + types.RankOfSquare(source))
+		}
+		if pos.IsCapture(m) {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(types.SquareToString(target))
+	}
+
+	next := *pos
+	next.MakeMove(m)
+	if next.IsInCheck(next.SideToMove) {
+		if len(next.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// sanPieces are the letters of the piece types in the Standard Algebraic Notation.
+var sanPieces = [types.PIECE_TYPE_NUMBER]byte{'P', 'N', 'B', 'R', 'Q', 'K'}
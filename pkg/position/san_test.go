@@ -0,0 +1,140 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPosition_MoveFromSAN(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		san     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "pawn push",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:  "e4",
+			want: "e2e4",
+		},
+		{
+			name: "knight move",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:  "Nf3",
+			want: "g1f3",
+		},
+		{
+			name: "pawn capture with check annotation",
+			fen:  "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2",
+			san:  "exd5!?",
+			want: "e4d5",
+		},
+		{
+			name: "en passant",
+			fen:  "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3",
+			san:  "exd6",
+			want: "e5d6",
+		},
+		{
+			name: "castling king side",
+			fen:  "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1",
+			san:  "O-O",
+			want: "e1g1",
+		},
+		{
+			name: "castling queen side with zeros",
+			fen:  "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1",
+			san:  "0-0-0",
+			want: "e8c8",
+		},
+		{
+			name: "disambiguation by file",
+			fen:  "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1",
+			san:  "Rad1",
+			want: "a1d1",
+		},
+		{
+			name: "promotion",
+			fen:  "8/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			san:  "e8=Q+",
+			want: "e7e8q",
+		},
+		{
+			name:    "ambiguous move",
+			fen:     "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1",
+			san:     "Rd1",
+			wantErr: true,
+		},
+		{
+			name:    "illegal move",
+			fen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:     "e5",
+			wantErr: true,
+		},
+		{
+			name:    "invalid move",
+			fen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:     "Xz9",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := NewFromFen(tt.fen)
+			require.NoError(t, err)
+			got, err := pos.MoveFromSAN(tt.san)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestPosition_SAN(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		want string
+	}{
+		{name: "pawn push", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", move: "e2e4", want: "e4"},
+		{name: "knight move", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", move: "g1f3", want: "Nf3"},
+		{name: "pawn capture", fen: "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", move: "e4d5", want: "exd5"},
+		{name: "en passant", fen: "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3", move: "e5d6", want: "exd6"},
+		{name: "castling king side", fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1", move: "e1g1", want: "O-O"},
+		{name: "castling queen side", fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1", move: "e8c8", want: "O-O-O"},
+		{name: "disambiguation by file", fen: "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", move: "a1d1", want: "Rad1"},
+		{name: "disambiguation by rank", fen: "4k3/8/8/R7/8/8/8/R5K1 w - - 0 1", move: "a1a3", want: "R1a3"},
+		{name: "disambiguation by square", fen: "4k3/8/8/8/Q1Q5/8/Q7/6K1 w - - 0 1", move: "a4b3", want: "Qa4b3"},
+		{name: "no disambiguation for a pinned piece", fen: "4k3/4r3/8/8/8/8/4N3/2N1K3 w - - 0 1", move: "c1d3", want: "Nd3"},
+		{name: "capture", fen: "4k3/8/8/3p4/8/2N5/8/4K3 w - - 0 1", move: "c3d5", want: "Nxd5"},
+		{name: "promotion with check", fen: "k7/4P3/8/8/8/8/8/4K3 w - - 0 1", move: "e7e8q", want: "e8=Q+"},
+		{name: "checkmate", fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", move: "a1a8", want: "Ra8#"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := NewFromFen(tt.fen)
+			require.NoError(t, err)
+			m, err := pos.MoveFromString(tt.move)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pos.SAN(m))
+		})
+	}
+}
+
+func TestPosition_SAN_roundTrip(t *testing.T) {
+	pos, err := NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	for _, m := range pos.LegalMoves() {
+		got, err := pos.MoveFromSAN(pos.SAN(m))
+		require.NoError(t, err)
+		assert.Equal(t, m, got, pos.SAN(m))
+	}
+}
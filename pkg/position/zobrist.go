@@ -7,8 +7,11 @@ import (
 	"github.com/shaardie/clemens/pkg/types"
 )
 
+// zobristSeed is the fixed seed of the zobrist keys, so the hashes are the same in every run.
+const zobristSeed = 281954
+
 var (
-	rnd rand.Source64 = rand.New(rand.NewSource(281954))
+	rnd rand.Source64 = rand.New(rand.NewSource(zobristSeed))
 )
 
 type zobrist struct {
@@ -39,6 +42,36 @@ func init() {
 	}
 }
 
+// ZobristFingerprint returns a fingerprint of the seed and all zobrist keys.
+// Zobrist hashes, e.g. in a stored transposition table, are only valid for the same fingerprint.
+func ZobristFingerprint() uint64 {
+	// FNV-1a over the seed and the keys
+	h := uint64(14695981039346656037)
+	add := func(v uint64) {
+		for range 8 {
+			h ^= v & 0xff
+			h *= 1099511628211
+			v >>= 8
+		}
+	}
+	add(zobristSeed)
+	for _, pieces := range z.piecesOnSquares {
+		for _, pieceTypes := range pieces {
+			for _, key := range pieceTypes {
+				add(key)
+			}
+		}
+	}
+	add(z.sideToMoveIsBlack)
+	for _, key := range z.castling {
+		add(key)
+	}
+	for _, key := range z.enPassant {
+		add(key)
+	}
+	return h
+}
+
 func (pos *Position) initZobristHash() {
 	pos.ZobristHash = 0
 
@@ -21,3 +21,14 @@ func TestPosition_ZobristHash(t *testing.T) {
 	reinitHash := pos.ZobristHash
 	assert.Equal(t, reinitHash, afterMoveHash)
 }
+
+func TestZobristFingerprint(t *testing.T) {
+	fingerprint := ZobristFingerprint()
+	assert.Equal(t, fingerprint, ZobristFingerprint())
+
+	// Any other key changes the fingerprint
+	key := z.castling[0]
+	defer func() { z.castling[0] = key }()
+	z.castling[0]++
+	assert.NotEqual(t, fingerprint, ZobristFingerprint())
+}
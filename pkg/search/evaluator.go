@@ -0,0 +1,45 @@
+package search
+
+import (
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// Evaluator evaluates positions for the search.
+// All threads of a search share the evaluator, so it has to be safe for concurrent use.
+type Evaluator interface {
+	// Evaluate returns the static evaluation of the position in centipawns from the view of the side to move.
+	Evaluate(pos *position.Position) int16
+	// PieceValue returns the value of the piece type used for pruning decisions.
+	PieceValue(pt types.PieceType) int16
+	// IsEndgame returns true, if the position is in the endgame.
+	IsEndgame(pos *position.Position) bool
+	// IsPawnEndgame returns true, if there are only kings and pawns on the board.
+	IsPawnEndgame(pos *position.Position) bool
+}
+
+// Make sure the evaluators implement the interface.
+var (
+	_ Evaluator = evaluation.HandCrafted{}
+	_ Evaluator = evaluation.Material{}
+)
+
+// evaluate returns the static evaluation of the position including the noise of the skill level.
+func (s *Search) evaluate(pos *position.Position) int16 {
+	e := s.Evaluator.Evaluate(pos)
+	if s.skill == nil {
+		return e
+	}
+	return e + s.skill.noise(pos)
+}
+
+// storedEval returns the static evaluation to store in the transposition table.
+// The noise of a weakened search depends on the skill, so it is never stored.
+func (s *Search) storedEval(e int16) int16 {
+	if s.skill != nil {
+		return transpositiontable.NoStaticEval
+	}
+	return e
+}
@@ -1,6 +1,14 @@
 package search
 
-import "github.com/shaardie/clemens/pkg/position"
+import (
+	"fmt"
+	"slices"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+	"github.com/shaardie/clemens/pkg/types"
+)
 
 func (s *Search) pushHistory(pos *position.Position) {
 	s.searchHistory[s.searchHistoryPly] = pos.ZobristHash
@@ -29,3 +37,81 @@ func (s *Search) MakeMoveFromString(m string) error {
 	s.pushHistory(&s.Pos)
 	return nil
 }
+
+// SetPosition sets the position given by the start position and the moves in the long algebraic notation.
+// If the position extends the current one, e.g. during a game, only the new moves are made
+// and the move ordering heuristics are kept, so the search is warm from the first iteration.
+// Otherwise the search is reset. It returns true, if the search state was kept.
+func (s *Search) SetPosition(start position.Position, moves []string) (bool, error) {
+	reused := start == s.startPos && len(moves) >= len(s.moves) && slices.Equal(moves[:len(s.moves)], s.moves)
+
+	// Drop a ponderhit of the search of the previous position
+	select {
+	case <-s.ponderHit:
+	default:
+	}
+
+	if reused {
+		newMoves := moves[len(s.moves):]
+		s.age(len(newMoves))
+		moves = newMoves
+	} else {
+		s.reset(start)
+	}
+	for _, m := range moves {
+		if err := s.MakeMoveFromString(m); err != nil {
+			return reused, fmt.Errorf("move %v, %w", m, err)
+		}
+		s.moves = append(s.moves, m)
+	}
+	return reused, nil
+}
+
+// reset resets the search to the position.
+func (s *Search) reset(pos position.Position) {
+	s.Pos = pos
+	s.startPos = pos
+	s.moves = nil
+	s.searchHistory = [1024]uint64{}
+	s.searchHistoryPly = 0
+	s.PV = pvline.PVLine{}
+	s.KillerMoves = [1024][2]move.Move{}
+	s.history = [types.COLOR_NUMBER][types.SQUARE_NUMBER][types.SQUARE_NUMBER]historyEntry{}
+	s.counter = [types.COLOR_NUMBER][types.SQUARE_NUMBER][types.SQUARE_NUMBER]move.Move{}
+	s.continuationHistory = continuationHistory{}
+	s.captureHistory = captureHistory{}
+	s.correctionHistory = correctionHistory{}
+}
+
+// age prepares the move ordering heuristics for a position the given number of plies later in the game.
+// The histories of the moves are halved, so the new results are more important than the old ones,
+// and the killer moves are shifted to the plies, they are found in the new search.
+func (s *Search) age(plies int) {
+	for c := range s.history {
+		for i := range s.history[c] {
+			for j := range s.history[c][i] {
+				s.history[c][i][j] /= 2
+			}
+		}
+	}
+	for i := range s.continuationHistory {
+		for j := range s.continuationHistory[i] {
+			for k := range s.continuationHistory[i][j] {
+				for l := range s.continuationHistory[i][j][k] {
+					s.continuationHistory[i][j][k][l] /= 2
+				}
+			}
+		}
+	}
+	for i := range s.captureHistory {
+		for j := range s.captureHistory[i] {
+			for k := range s.captureHistory[i][j] {
+				s.captureHistory[i][j][k] /= 2
+			}
+		}
+	}
+	plies = min(plies, len(s.KillerMoves))
+	copy(s.KillerMoves[:], s.KillerMoves[plies:])
+	clear(s.KillerMoves[len(s.KillerMoves)-plies:])
+	s.PV = pvline.PVLine{}
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_SetPosition(t *testing.T) {
+	start := *position.New()
+	var killer move.Move
+	killer.SetSourceSquare(types.SQUARE_B1)
+	killer.SetTargetSquare(types.SQUARE_C3)
+
+	tests := []struct {
+		name       string
+		start      position.Position
+		moves      []string
+		wantReused bool
+		wantFen    string
+		wantErr    bool
+	}{
+		{
+			name:       "game continues",
+			start:      start,
+			moves:      []string{"e2e4", "e7e5", "g1f3", "b8c6"},
+			wantReused: true,
+			wantFen:    "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		},
+		{
+			name:       "same position",
+			start:      start,
+			moves:      []string{"e2e4", "e7e5"},
+			wantReused: true,
+			wantFen:    "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		},
+		{
+			name:       "other moves",
+			start:      start,
+			moves:      []string{"d2d4"},
+			wantReused: false,
+			wantFen:    "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1",
+		},
+		{
+			name:       "broken move",
+			start:      start,
+			moves:      []string{"e2e4", "e7e5", "x"},
+			wantReused: true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearch(start, DefaultTables)
+			_, err := s.SetPosition(start, []string{"e2e4", "e7e5"})
+			require.NoError(t, err)
+			s.history[types.WHITE][types.SQUARE_G1][types.SQUARE_F3] = 100
+			s.KillerMoves[3][0] = killer
+
+			reused, err := s.SetPosition(tt.start, tt.moves)
+			assert.Equal(t, tt.wantReused, reused)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFen, s.Pos.ToFen())
+			assert.Equal(t, tt.moves, s.moves)
+			assert.Equal(t, len(tt.moves), s.searchHistoryPly)
+
+			if !tt.wantReused {
+				assert.Equal(t, historyEntry(0), s.history[types.WHITE][types.SQUARE_G1][types.SQUARE_F3])
+				assert.Equal(t, [2]move.Move{}, s.KillerMoves[3])
+				return
+			}
+			// The state is aged
+			newMoves := len(tt.moves) - 2
+			assert.Equal(t, historyEntry(50), s.history[types.WHITE][types.SQUARE_G1][types.SQUARE_F3])
+			assert.Equal(t, killer, s.KillerMoves[3-newMoves][0])
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"sync"
+)
+
+// Lazy SMP, see https://www.chessprogramming.org/Lazy_SMP
+// The helper threads search the same root position as the main thread on their own copy of the position.
+// The only thing they share with the main thread is the transposition table,
+// so the main thread benefits from the results of the helpers.
+// Only the main thread prints information and determines the best move.
+
+// newHelper creates a helper thread for the current search of the main thread.
+func (s *Search) newHelper(ctx context.Context) *Search {
+	return &Search{
+		ctx:                  ctx,
+		Pos:                  s.Pos,
+		searchHistory:        s.searchHistory,
+		searchHistoryPly:     s.searchHistoryPly,
+		rootMoves:            s.rootMoves,
+		isRootRestricted:     s.isRootRestricted,
+		mateSearch:           s.mateSearch,
+		disabledPruning:      s.disabledPruning,
+		useCorrectionHistory: s.useCorrectionHistory,
+		tables:               s.tables,
+		Evaluator:            s.Evaluator,
+		Reporter:             NoReporter{},
+		start:                s.start,
+	}
+}
+
+// startHelpers starts the number of helper threads in the background.
+// The helpers run until they reach the maximal depth or the context is done.
+func (s *Search) startHelpers(ctx context.Context, number int, maxDepth uint8) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	s.helpers = make([]*Search, max(number, 0))
+	for i := range s.helpers {
+		s.helpers[i] = s.newHelper(ctx)
+	}
+	for i, h := range s.helpers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every second helper starts one depth later to desynchronize the threads.
+			h.searchIterative(uint8(1+i%2), maxDepth)
+		}()
+	}
+	return wg
+}
+
+// totalNodes returns the number of nodes searched by the main thread and all helpers.
+func (s *Search) totalNodes() uint64 {
+	nodes := s.nodes.Load()
+	for _, h := range s.helpers {
+		nodes += h.nodes.Load()
+	}
+	return nodes
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// History Heuristics, see https://www.chessprogramming.org/History_Heuristic
+// All history tables are updated with the so called gravity formula,
+// which keeps the entries within [-maxHistory, maxHistory] without rescaling the whole table.
+// Large bonuses on entries close to the limit have less effect than on small entries.
+
+const (
+	// maxHistory is the maximal absolute value of a history entry.
+	maxHistory = 16384
+	// maxHistoryBonus is the maximal bonus for a single update.
+	maxHistoryBonus = 1536
+	// pieceIndexNumber is the number of indices for a types.Piece, which has a gap between the colors.
+	pieceIndexNumber = types.BLACK_KING + 1
+	// maxQuietsSearched is the number of searched quiet moves and captures, which get a malus on a cutoff.
+	maxQuietsSearched = 64
+)
+
+type historyEntry int16
+
+// update applies the bonus, which might be negative for a malus, with the gravity formula.
+func (h *historyEntry) update(bonus int) {
+	bonus = max(min(bonus, maxHistory), -maxHistory)
+	absBonus := bonus
+	if absBonus < 0 {
+		absBonus = -absBonus
+	}
+	*h += historyEntry(bonus - int(*h)*absBonus/maxHistory)
+}
+
+// historyBonus returns the bonus for a cutoff at the depth.
+func historyBonus(depth uint8) int {
+	return min(16*int(depth)*int(depth)+32*int(depth), maxHistoryBonus)
+}
+
+// pieceToHistory is indexed by the moved piece and the target square.
+type pieceToHistory [pieceIndexNumber][types.SQUARE_NUMBER]historyEntry
+
+// continuationHistory is indexed by the moved piece and the target square of a previous move.
+// The same table is used for the moves one and two plies ago.
+// https://www.chessprogramming.org/History_Heuristic#Continuation_History
+type continuationHistory [pieceIndexNumber][types.SQUARE_NUMBER]pieceToHistory
+
+// captureHistory is indexed by the moved piece, the target square and the type of the captured piece.
+type captureHistory [pieceIndexNumber][types.SQUARE_NUMBER][types.PIECE_TYPE_NUMBER]historyEntry
+
+// continuation returns the continuation history of the move the given number of plies ago,
+// or nil, if there is no such move, e.g. at the root or after a null move.
+func (s *Search) continuation(ply, pliesAgo uint8) *pieceToHistory {
+	if ply < pliesAgo {
+		return nil
+	}
+	e := &s.stack[ply-pliesAgo]
+	if e.currentMove == move.NullMove {
+		return nil
+	}
+	return &s.continuationHistory[e.movedPiece][e.currentMove.GetTargetSquare()]
+}
+
+// quietHistory returns the sum of the butterfly and the continuation histories for a quiet move.
+func (s *Search) quietHistory(pos *position.Position, m move.Move, ply uint8) int {
+	piece := pos.PiecesBoard[m.GetSourceSquare()]
+	target := m.GetTargetSquare()
+	score := int(s.history[pos.SideToMove][m.GetSourceSquare()][target])
+	for _, pliesAgo := range [...]uint8{1, 2} {
+		if c := s.continuation(ply, pliesAgo); c != nil {
+			score += int(c[piece][target])
+		}
+	}
+	return score
+}
+
+// updateQuietHistory applies the bonus to the butterfly and the continuation histories of a quiet move.
+func (s *Search) updateQuietHistory(pos *position.Position, m move.Move, ply uint8, bonus int) {
+	piece := pos.PiecesBoard[m.GetSourceSquare()]
+	target := m.GetTargetSquare()
+	s.history[pos.SideToMove][m.GetSourceSquare()][target].update(bonus)
+	for _, pliesAgo := range [...]uint8{1, 2} {
+		if c := s.continuation(ply, pliesAgo); c != nil {
+			c[piece][target].update(bonus)
+		}
+	}
+}
+
+// capturedPieceType returns the type of the piece captured by the move.
+func capturedPieceType(pos *position.Position, m move.Move) types.PieceType {
+	if m.GetMoveType() == move.EN_PASSANT {
+		return types.PAWN
+	}
+	return pos.PiecesBoard[m.GetTargetSquare()].Type()
+}
+
+// captureHistoryEntry returns the capture history entry of the capture.
+func (s *Search) captureHistoryEntry(pos *position.Position, m move.Move) *historyEntry {
+	return &s.captureHistory[pos.PiecesBoard[m.GetSourceSquare()]][m.GetTargetSquare()][capturedPieceType(pos, m)]
+}
+
+// updateHistories rewards the move, which caused a beta cutoff, and punishes the moves searched before.
+// A quiet move also becomes a killer and counter move.
+func (s *Search) updateHistories(pos *position.Position, bestMove move.Move, ply, depth uint8, quietsSearched, capturesSearched []move.Move) {
+	bonus := historyBonus(depth)
+
+	if !pos.IsCapture(bestMove) {
+		// Update Killer Move
+		if s.KillerMoves[ply][0] != bestMove {
+			s.KillerMoves[ply][1] = s.KillerMoves[ply][0]
+		}
+		s.KillerMoves[ply][0] = bestMove
+
+		// Update counter moves
+		if previousMove := s.stack.previousMove(ply); previousMove != move.NullMove {
+			s.counter[pos.SideToMove][previousMove.GetSourceSquare()][previousMove.GetTargetSquare()] = bestMove
+		}
+
+		s.updateQuietHistory(pos, bestMove, ply, bonus)
+		for _, m := range quietsSearched {
+			s.updateQuietHistory(pos, m, ply, -bonus)
+		}
+	} else {
+		s.captureHistoryEntry(pos, bestMove).update(bonus)
+	}
+
+	// The captures are searched first, so they failed in any case
+	for _, m := range capturesSearched {
+		s.captureHistoryEntry(pos, m).update(-bonus)
+	}
+}
+
+// Static Evaluation Correction History
+// The difference between the result of the search and the static evaluation is remembered by the pawn structure,
+// so the static evaluation is corrected for errors of the evaluation, which are typical for the pawn structure.
+
+const (
+	correctionHistorySize = 16384
+	// correctionHistoryGrain is the resolution of the entries, which are in 1/grain centipawns.
+	correctionHistoryGrain = 128
+	// correctionHistoryWeightScale is the scale of the weight of a new result depending on the depth.
+	correctionHistoryWeightScale = 256
+	// maxCorrection is the maximal correction in 1/grain centipawns.
+	maxCorrection = 64 * correctionHistoryGrain
+)
+
+type correctionHistory [types.COLOR_NUMBER][correctionHistorySize]historyEntry
+
+// pawnIndex returns the index of the pawn structure in the correction history.
+func pawnIndex(pos *position.Position) uint64 {
+	h := uint64(pos.PiecesBitboard[types.WHITE][types.PAWN])*0x9E3779B97F4A7C15 ^
+		uint64(pos.PiecesBitboard[types.BLACK][types.PAWN])*0xC2B2AE3D27D4EB4F
+	h ^= h >> 29
+	return h % correctionHistorySize
+}
+
+// correctEvaluation returns the static evaluation corrected by the correction history.
+func (s *Search) correctEvaluation(pos *position.Position, staticEval int16) int16 {
+	correction := int(s.correctionHistory[pos.SideToMove][pawnIndex(pos)]) / correctionHistoryGrain
+	corrected := int(staticEval) + correction
+	// The corrected evaluation must never be a checkmate value
+	limit := int(evaluation.INF) - 2*int(max_depth)
+	return int16(max(min(corrected, limit), -limit))
+}
+
+// updateCorrectionHistory moves the correction of the pawn structure towards the difference
+// between the result of the search and the static evaluation.
+func (s *Search) updateCorrectionHistory(pos *position.Position, depth uint8, staticEval, score int16) {
+	entry := &s.correctionHistory[pos.SideToMove][pawnIndex(pos)]
+	weight := min(int(depth)+1, 16)
+	diff := (int(score) - int(staticEval)) * correctionHistoryGrain
+	value := (int(*entry)*(correctionHistoryWeightScale-weight) + diff*weight) / correctionHistoryWeightScale
+	*entry = historyEntry(max(min(value, maxCorrection), -maxCorrection))
+}
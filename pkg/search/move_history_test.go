@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_historyEntry_update(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   historyEntry
+		bonuses []int
+		want    historyEntry
+	}{
+		{
+			name:    "bonus",
+			bonuses: []int{100},
+			want:    100,
+		},
+		{
+			name:    "malus",
+			bonuses: []int{-100},
+			want:    -100,
+		},
+		{
+			name:    "gravity",
+			entry:   maxHistory / 2,
+			bonuses: []int{1000},
+			want:    maxHistory/2 + 500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.entry
+			for _, b := range tt.bonuses {
+				h.update(b)
+			}
+			assert.Equal(t, tt.want, h)
+		})
+	}
+}
+
+func Test_historyEntry_updateBounded(t *testing.T) {
+	var h historyEntry
+	for range 1000 {
+		h.update(maxHistoryBonus)
+		assert.LessOrEqual(t, h, historyEntry(maxHistory))
+	}
+	for range 1000 {
+		h.update(-maxHistoryBonus)
+		assert.GreaterOrEqual(t, h, historyEntry(-maxHistory))
+	}
+}
+
+func TestSearch_updateHistories(t *testing.T) {
+	pos, err := position.NewFromFen("4k3/8/8/3p3n/8/8/8/3QK2R w - - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, DefaultTables)
+
+	previousMove := *new(move.Move).SetSourceSquare(types.SQUARE_E7).SetTargetSquare(types.SQUARE_E8)
+	s.stack[0].currentMove = previousMove
+	s.stack[0].movedPiece = types.BLACK_KING
+
+	cutoff := *new(move.Move).SetSourceSquare(types.SQUARE_H1).SetTargetSquare(types.SQUARE_H4)
+	quiet := *new(move.Move).SetSourceSquare(types.SQUARE_D1).SetTargetSquare(types.SQUARE_D4)
+	capture := *new(move.Move).SetSourceSquare(types.SQUARE_H1).SetTargetSquare(types.SQUARE_H5)
+	s.updateHistories(pos, cutoff, 1, 5, []move.Move{quiet}, []move.Move{capture})
+
+	bonus := historyEntry(historyBonus(5))
+	assert.Equal(t, cutoff, s.KillerMoves[1][0])
+	assert.Equal(t, cutoff, s.counter[types.WHITE][types.SQUARE_E7][types.SQUARE_E8])
+	assert.Equal(t, bonus, s.history[types.WHITE][types.SQUARE_H1][types.SQUARE_H4])
+	assert.Equal(t, bonus, s.continuationHistory[types.BLACK_KING][types.SQUARE_E8][types.WHITE_ROOK][types.SQUARE_H4])
+	assert.Equal(t, -bonus, s.history[types.WHITE][types.SQUARE_D1][types.SQUARE_D4])
+	assert.Equal(t, -bonus, s.continuationHistory[types.BLACK_KING][types.SQUARE_E8][types.WHITE_QUEEN][types.SQUARE_D4])
+	assert.Equal(t, -bonus, s.captureHistory[types.WHITE_ROOK][types.SQUARE_H5][types.KNIGHT])
+	assert.Greater(t, s.quietHistory(pos, cutoff, 1), s.quietHistory(pos, quiet, 1))
+
+	// A capture does not become a killer move
+	s.updateHistories(pos, capture, 1, 5, nil, nil)
+	assert.Equal(t, cutoff, s.KillerMoves[1][0])
+	assert.Greater(t, s.captureHistory[types.WHITE_ROOK][types.SQUARE_H5][types.KNIGHT], historyEntry(0))
+}
+
+func TestSearch_correctionHistory(t *testing.T) {
+	pos, err := position.NewFromFen("4k3/pp6/8/8/8/8/PP6/4K3 w - - 0 1")
+	require.NoError(t, err)
+	// The same pawn structure with other pieces
+	other, err := position.NewFromFen("3qk3/pp6/8/8/8/8/PP6/3QK3 w - - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, DefaultTables)
+
+	assert.Equal(t, int16(10), s.correctEvaluation(pos, 10))
+	for range 100 {
+		s.updateCorrectionHistory(pos, 15, 10, 50)
+	}
+	// The correction approaches the difference of 40 for the pawn structure
+	assert.InDelta(t, 50, s.correctEvaluation(pos, 10), 2)
+	assert.InDelta(t, 50, s.correctEvaluation(other, 10), 2)
+
+	// The correction is bounded
+	for range 100 {
+		s.updateCorrectionHistory(pos, 15, 0, 1000)
+	}
+	assert.Equal(t, int16(maxCorrection/correctionHistoryGrain), s.correctEvaluation(pos, 0))
+}
+
+func TestSearchCorrectionHistory(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+
+	// The correction history is only learned, if it is enabled
+	for _, enabled := range []bool{false, true} {
+		s := NewSearch(*pos, NewTables(1, 1))
+		s.Search(context.TODO(), SearchParameter{Depth: 6, Infinite: true, CorrectionHistory: enabled})
+		assert.Equal(t, enabled, s.correctionHistory != correctionHistory{})
+	}
+}
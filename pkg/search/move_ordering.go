@@ -1,17 +1,27 @@
 package search
 
 import (
+	"github.com/shaardie/clemens/pkg/evaluation"
 	"github.com/shaardie/clemens/pkg/move"
 	"github.com/shaardie/clemens/pkg/position"
 	"github.com/shaardie/clemens/pkg/types"
 )
 
+// The moves are ordered by their score in the following order:
+// principal variation move, transposition table move, good captures, promotions, killer moves, counter move,
+// quiet moves by their history and at last the captures losing material in the static exchange evaluation.
 const (
-	pvMoveScore     = 1000
-	ttMoveScore     = 900
-	killerMoveScore = 100
-	promotionScore  = 500
-	couterMoveBonus = 10
+	pvMoveScore       = 60000
+	ttMoveScore       = 59000
+	goodCaptureScore  = 40000
+	promotionScore    = 35000
+	killerMoveScore   = 30000
+	counterMoveScore  = killerMoveScore - 2
+	quietMoveScore    = 15000
+	badCaptureScore   = 1000
+	mvvLvaScale       = 64
+	captureHistoryDiv = 32
+	quietHistoryDiv   = 4
 )
 
 // Static Values for MVV-LVA Ordering
@@ -25,9 +35,7 @@ func init() {
 	victim := types.QUEEN
 	for {
 		for aggressor := types.PAWN; aggressor < types.PIECE_TYPE_NUMBER; aggressor++ {
-			MVV_LVA_SCORES[victim][aggressor] = uint16(
-				(10*(victim+1) - (aggressor)) + killerMoveScore,
-			)
+			MVV_LVA_SCORES[victim][aggressor] = uint16(10*(victim+1) - (aggressor))
 		}
 		if victim == types.PAWN {
 			break
@@ -37,6 +45,8 @@ func init() {
 }
 
 func (s *Search) scoreMoves(pos *position.Position, moves *move.MoveList, pvMove, ttMove, previousMove move.Move, ply uint8) {
+	pvMove = pvMove.WithoutScore()
+	ttMove = ttMove.WithoutScore()
 	counterMove := move.NullMove
 	if previousMove != move.NullMove {
 		counterMove = s.counter[pos.SideToMove][previousMove.GetSourceSquare()][previousMove.GetTargetSquare()]
@@ -57,21 +67,7 @@ func (s *Search) scoreMoves(pos *position.Position, moves *move.MoveList, pvMove
 			continue
 		}
 
-		// No capture
-		targetSquare := m.GetTargetSquare()
-		sourceSquare := m.GetSourceSquare()
-		var target types.Piece
-		if m.GetMoveType() == move.EN_PASSANT {
-			if pos.SideToMove == types.BLACK {
-				target = types.WHITE_PAWN
-			} else {
-				target = types.BLACK_PAWN
-			}
-		} else {
-			target = pos.GetPiece(targetSquare)
-		}
-
-		if target == types.NO_PIECE {
+		if !pos.IsCapture(*m) {
 			if m.GetMoveType() == move.PROMOTION {
 				m.SetScore(promotionScore)
 				continue
@@ -86,20 +82,25 @@ func (s *Search) scoreMoves(pos *position.Position, moves *move.MoveList, pvMove
 				m.SetScore(killerMoveScore - 1)
 				continue
 			}
-
-			score := s.history[pos.SideToMove][sourceSquare][targetSquare]
-			if counterMove == *m {
-				score += couterMoveBonus
+			if *m == counterMove {
+				m.SetScore(counterMoveScore)
+				continue
 			}
 
-			m.SetScore(score)
+			m.SetScore(uint16(quietMoveScore + s.quietHistory(pos, *m, ply)/quietHistoryDiv))
 			continue
-
 		}
 
-		// Captures are placed above the killer moves. So add the killer move score to all of them.
-		source := pos.GetPiece(sourceSquare)
-		m.SetScore(MVV_LVA_SCORES[target.Type()][source.Type()] + promotionScore)
+		// Captures are ordered by MVV-LVA and the capture history.
+		// Captures losing material in the static exchange evaluation are placed below the quiet moves.
+		source := pos.GetPiece(m.GetSourceSquare())
+		score := int(MVV_LVA_SCORES[capturedPieceType(pos, *m)][source.Type()])
+		history := int(*s.captureHistoryEntry(pos, *m))
+		if !evaluation.StaticExchangeEvaluationAtLeast(pos, m, 0) {
+			m.SetScore(uint16(badCaptureScore + score*mvvLvaScale/8 + history/captureHistoryDiv/2))
+			continue
+		}
+		m.SetScore(uint16(goodCaptureScore + score*mvvLvaScale + history/captureHistoryDiv))
 	}
 }
 
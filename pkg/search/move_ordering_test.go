@@ -6,6 +6,7 @@ import (
 
 	"github.com/shaardie/clemens/pkg/move"
 	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,7 +16,7 @@ func TestSearch_orderMoves(t *testing.T) {
 	require.NoError(t, err)
 
 	// Generate all moves and order them
-	s := NewSearch(*pos)
+	s := NewSearch(*pos, DefaultTables)
 	s.Search(context.TODO(), SearchParameter{Depth: 5})
 
 	moves1 := move.NewMoveList()
@@ -33,3 +34,25 @@ func TestSearch_orderMoves(t *testing.T) {
 		assert.Equal(t, moves1.Get(i).GetScore(), moves2.Get(i).GetScore())
 	}
 }
+
+func TestSearch_scoreMovesLosingCaptures(t *testing.T) {
+	pos, err := position.NewFromFen("4k3/8/2p5/3p3n/8/8/8/3QK2R w - - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, DefaultTables)
+
+	killer := *new(move.Move).SetSourceSquare(types.SQUARE_H1).SetTargetSquare(types.SQUARE_H2)
+	goodCapture := *new(move.Move).SetSourceSquare(types.SQUARE_H1).SetTargetSquare(types.SQUARE_H5)
+	badCapture := *new(move.Move).SetSourceSquare(types.SQUARE_D1).SetTargetSquare(types.SQUARE_D5)
+	s.KillerMoves[0][0] = killer
+
+	moves := move.NewMoveList()
+	pos.GeneratePseudoLegalMoves(moves)
+	s.scoreMoves(pos, moves, move.NullMove, move.NullMove, move.NullMove, 0)
+	scores := map[move.Move]uint16{}
+	for i := range moves.Length() {
+		m := moves.Get(i)
+		scores[m.WithoutScore()] = m.GetScore()
+	}
+	assert.Greater(t, scores[goodCapture], scores[killer])
+	assert.Less(t, scores[badCapture], scores[killer])
+}
@@ -0,0 +1,69 @@
+package search
+
+import "github.com/shaardie/clemens/pkg/evaluation"
+
+// Pruning is a set of optional pruning techniques and other selective parts of the search.
+// All techniques are enabled by default and can be disabled one by one,
+// e.g. to measure their strength in a SPRT.
+type Pruning uint8
+
+const (
+	// LateMovePruning skips the late quiet moves at shallow depth.
+	// https://www.chessprogramming.org/Futility_Pruning#MoveCountBasedPruning
+	LateMovePruning Pruning = 1 << iota
+	// SEEPruning skips moves losing too much material in the static exchange evaluation at shallow depth.
+	SEEPruning
+	// Razoring drops into the quiescence search, if the static evaluation is far below alpha.
+	// https://www.chessprogramming.org/Razoring
+	Razoring
+	// InternalIterativeReduction reduces the depth of nodes without a transposition table move.
+	// https://www.chessprogramming.org/Internal_Iterative_Reductions
+	InternalIterativeReduction
+	// QuiescenceChecks searches the quiet checks at the first ply of the quiescence search.
+	QuiescenceChecks
+)
+
+const (
+	// lateMovePruningDepth is the maximal depth for the late move pruning.
+	lateMovePruningDepth uint8 = 6
+	// seePruningDepth is the maximal depth for the static exchange evaluation pruning.
+	seePruningDepth uint8 = 6
+	// seeQuietMargin is the loss of material per squared depth a quiet move may have.
+	seeQuietMargin int16 = 20
+	// seeCaptureMargin is the loss of material per depth a capture may have.
+	seeCaptureMargin int16 = 100
+	// razoringDepth is the maximal depth for razoring.
+	razoringDepth uint8 = 3
+	// internalIterativeReductionDepth is the minimal depth for the internal iterative reduction.
+	internalIterativeReductionDepth uint8 = 4
+)
+
+// razoringMargin is the margin the static evaluation has to be below alpha by depth.
+var razoringMargin = [razoringDepth + 1]int16{0, 250, 400, 600}
+
+// isEnabled returns true, if the pruning technique is not disabled for the search.
+func (s *Search) isEnabled(p Pruning) bool {
+	return s.disabledPruning&p == 0
+}
+
+// lateMovePruningThreshold returns the number of quiet moves searched, before the remaining ones are skipped.
+func lateMovePruningThreshold(depth uint8, improving bool) int {
+	threshold := 3 + int(depth)*int(depth)
+	if !improving {
+		threshold /= 2
+	}
+	return threshold
+}
+
+// seePruningThreshold returns the minimal static exchange evaluation of a move, which is not pruned.
+func seePruningThreshold(depth uint8, isCapture bool) int16 {
+	if isCapture {
+		return -seeCaptureMargin * int16(depth)
+	}
+	return -seeQuietMargin * int16(depth) * int16(depth)
+}
+
+// canPrune returns true, if moves might be skipped, because there is already a score, which is not a checkmate.
+func canPrune(bestScore int16) bool {
+	return bestScore > -evaluation.INF+int16(max_depth)
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_isEnabled(t *testing.T) {
+	s := &Search{disabledPruning: Razoring | SEEPruning}
+	assert.True(t, s.isEnabled(LateMovePruning))
+	assert.False(t, s.isEnabled(SEEPruning))
+	assert.False(t, s.isEnabled(Razoring))
+	assert.True(t, s.isEnabled(InternalIterativeReduction))
+}
+
+func Test_lateMovePruningThreshold(t *testing.T) {
+	assert.Equal(t, 4, lateMovePruningThreshold(1, true))
+	assert.Equal(t, 2, lateMovePruningThreshold(1, false))
+	assert.Equal(t, 39, lateMovePruningThreshold(6, true))
+	assert.Equal(t, 19, lateMovePruningThreshold(6, false))
+}
+
+func Test_seePruningThreshold(t *testing.T) {
+	assert.Equal(t, int16(-300), seePruningThreshold(3, true))
+	assert.Equal(t, int16(-180), seePruningThreshold(3, false))
+}
+
+func Test_canPrune(t *testing.T) {
+	assert.False(t, canPrune(-evaluation.INF))
+	assert.False(t, canPrune(-evaluation.INF+5))
+	assert.True(t, canPrune(-100))
+	assert.True(t, canPrune(evaluation.INF-5))
+}
+
+func TestSearchDisabledPruning(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+
+	techniques := []Pruning{LateMovePruning, SEEPruning, Razoring, InternalIterativeReduction, QuiescenceChecks}
+	all := LateMovePruning | SEEPruning | Razoring | InternalIterativeReduction | QuiescenceChecks
+	nodes := map[Pruning]uint64{}
+	for _, disabled := range append([]Pruning{0, all}, techniques...) {
+		tables := NewTables(1, 1)
+		s := NewSearch(*pos, tables)
+		bestMove := s.Search(context.TODO(), SearchParameter{Depth: 6, Infinite: true, DisabledPruning: disabled})
+		assert.NotEqual(t, move.NullMove, bestMove)
+		nodes[disabled] = s.totalNodes()
+	}
+	// The pruning techniques make the search smaller
+	assert.Less(t, nodes[0], nodes[all])
+	// and every technique is used on its own
+	for _, p := range techniques {
+		assert.NotEqual(t, nodes[0], nodes[p], "pruning %b", p)
+	}
+}
+
+func TestSearch_negamaxProbCutBelowMate(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, NewTables(1, 1))
+	s.ctx = context.TODO()
+
+	// Beta is just below the checkmate values, so beta plus the margin of ProbCut is not a valid score.
+	beta := evaluation.INF - 100
+	require.False(t, evaluation.IsCheckmateValue(beta))
+	// Without a move of the transposition table the depth is reduced once
+	score, err := s.negamax(pos, beta-1, beta, probCutDepth+1, 1, false)
+	require.NoError(t, err)
+	assert.Less(t, score, beta)
+
+	// No capture beats beta, so there is no lower bound in the transposition table
+	e, found := s.tables.TT.Probe(pos.ZobristHash, 1)
+	require.True(t, found)
+	assert.False(t, e.IsLowerBound())
+}
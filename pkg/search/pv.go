@@ -0,0 +1,91 @@
+package search
+
+import (
+	"slices"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+)
+
+// pvTable is a triangular table of the principal variations of all plies of the current search path.
+// The line of a ply starts with the best move of the ply followed by the line of the next ply,
+// so it has at most as many moves as there are plies left.
+// The table has a fixed size, so updating the lines does not allocate.
+// https://www.chessprogramming.org/Triangular_PV-Table
+type pvTable struct {
+	moves  [pvline.MaxLength * (pvline.MaxLength + 1) / 2]move.Move
+	length [pvline.MaxLength]uint16
+}
+
+// offset returns the index of the first move of the line of the ply.
+func (pv *pvTable) offset(ply uint8) int {
+	p := int(ply)
+	return p*pvline.MaxLength - p*(p-1)/2
+}
+
+// reset clears the line of the ply, which is done, whenever a node is entered.
+func (pv *pvTable) reset(ply uint8) {
+	pv.length[ply] = 0
+}
+
+// update sets the line of the ply to the move followed by the line of the next ply.
+func (pv *pvTable) update(ply uint8, m move.Move) {
+	o := pv.offset(ply)
+	pv.moves[o] = m
+	length := uint16(1)
+	if int(ply)+1 < pvline.MaxLength {
+		next := pv.offset(ply + 1)
+		length += uint16(copy(pv.moves[o+1:o+pvline.MaxLength-int(ply)], pv.moves[next:next+int(pv.length[ply+1])]))
+	}
+	pv.length[ply] = length
+}
+
+// line returns the moves of the line of the ply.
+func (pv *pvTable) line(ply uint8) []move.Move {
+	o := pv.offset(ply)
+	return pv.moves[o : o+int(pv.length[ply])]
+}
+
+// extendPV extends a line, which is truncated by a cutoff of the transposition table, by its best moves,
+// so the principal variation has at least the length of the depth.
+// Only legal moves are added and the extension stops at a repetition.
+func (s *Search) extendPV(line *pvline.PVLine, depth uint8) {
+	pos := s.Pos
+	var hashes [pvline.MaxLength + 1]uint64
+	hashes[0] = pos.ZobristHash
+	for i, m := range line.Moves() {
+		pos.MakeMove(m)
+		hashes[i+1] = pos.ZobristHash
+	}
+
+	moves := move.NewMoveList()
+	for line.Len() < int(depth) && line.Len() < pvline.MaxLength {
+		e, found := s.tables.TT.Probe(pos.ZobristHash, uint8(line.Len()))
+		if !found || !isPseudoLegal(&pos, moves, e.BestMove) {
+			return
+		}
+		pos.MakeMove(e.BestMove)
+		if !pos.IsLegal() || slices.Contains(hashes[:line.Len()+1], pos.ZobristHash) {
+			return
+		}
+		line.Append(e.BestMove)
+		hashes[line.Len()] = pos.ZobristHash
+	}
+}
+
+// isPseudoLegal returns true, if the move is one of the pseudo legal moves of the position.
+// A move from the transposition table might belong to another position with the same key.
+func isPseudoLegal(pos *position.Position, moves *move.MoveList, m move.Move) bool {
+	if m == move.NullMove {
+		return false
+	}
+	moves.Reset()
+	pos.GeneratePseudoLegalMoves(moves)
+	for i := range moves.Length() {
+		if moves.Get(i).WithoutScore() == m {
+			return true
+		}
+	}
+	return false
+}
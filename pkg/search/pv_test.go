@@ -0,0 +1,108 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pvTable(t *testing.T) {
+	var pv pvTable
+	// The lines of all plies fit into the table without overlapping
+	assert.Equal(t, len(pv.moves), pv.offset(pvline.MaxLength-1)+1)
+
+	pv.reset(3)
+	pv.update(2, 5)
+	assert.Equal(t, []move.Move{5}, pv.line(2))
+	pv.update(1, 4)
+	pv.update(0, 3)
+	assert.Equal(t, []move.Move{3, 4, 5}, pv.line(0))
+
+	// A new node clears its line
+	pv.reset(1)
+	pv.update(0, 6)
+	assert.Equal(t, []move.Move{6}, pv.line(0))
+
+	// The line of the last ply has only a single move
+	pv.update(pvline.MaxLength-1, 7)
+	assert.Equal(t, []move.Move{7}, pv.line(pvline.MaxLength-1))
+}
+
+func TestSearch_extendPV(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []string
+		line  []string
+		want  string
+	}{
+		{
+			name:  "extend from the root",
+			moves: []string{"e2e4", "e7e5"},
+			want:  "e2e4 e7e5",
+		},
+		{
+			name:  "extend a line",
+			moves: []string{"e2e4", "e7e5", "g1f3"},
+			line:  []string{"e2e4"},
+			want:  "e2e4 e7e5 g1f3",
+		},
+		{
+			name:  "limited by the depth",
+			moves: []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"},
+			want:  "e2e4 e7e5 g1f3 b8c6",
+		},
+		{
+			name:  "stop at a repetition",
+			moves: []string{"g1f3", "g8f6", "f3g1", "f6g8"},
+			want:  "g1f3 g8f6 f3g1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearch(*position.New(), NewTables(1, 1))
+			pos := *position.New()
+			for _, m := range tt.moves {
+				tm := moveFromString(t, &pos, m)
+				s.tables.TT.PotentiallySave(pos.ZobristHash, tm, 1, 0, 0, 0, transpositiontable.PVNode)
+				pos.MakeMove(tm)
+			}
+
+			var line pvline.PVLine
+			pos = *position.New()
+			for _, m := range tt.line {
+				tm := moveFromString(t, &pos, m)
+				line.Append(tm)
+				pos.MakeMove(tm)
+			}
+			s.extendPV(&line, 4)
+			assert.Equal(t, tt.want, line.String())
+		})
+	}
+
+	// A move, which is not legal in the position, is not added
+	s := NewSearch(*position.New(), NewTables(1, 1))
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(36)
+	s.tables.TT.PotentiallySave(s.Pos.ZobristHash, m, 1, 0, 0, 0, transpositiontable.PVNode)
+	var line pvline.PVLine
+	s.extendPV(&line, 4)
+	assert.Equal(t, 0, line.Len())
+}
+
+// moveFromString returns the pseudo legal move of the position in the UCI notation.
+func moveFromString(t *testing.T, pos *position.Position, s string) move.Move {
+	moves := move.NewMoveList()
+	pos.GeneratePseudoLegalMoves(moves)
+	for i := range moves.Length() {
+		if moves.Get(i).String() == s {
+			return *moves.Get(i)
+		}
+	}
+	require.Failf(t, "move not found", "%v", s)
+	return move.NullMove
+}
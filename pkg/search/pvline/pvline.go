@@ -1,14 +1,27 @@
 package pvline
 
 import (
-	"fmt"
+	"math"
 	"strings"
 
 	"github.com/shaardie/clemens/pkg/move"
 )
 
+// MaxLength is the maximal number of moves of a line, which is the number of plies of a search.
+const MaxLength = math.MaxUint8 + 1
+
+// PVLine is a line of moves with a fixed capacity,
+// so it is copied by an assignment without any allocation.
 type PVLine struct {
-	moves []move.Move
+	moves  [MaxLength]move.Move
+	length int
+}
+
+// New returns a line of the moves, which is truncated to MaxLength.
+func New(moves ...move.Move) PVLine {
+	var pvline PVLine
+	pvline.Set(moves)
+	return pvline
 }
 
 func (pvline *PVLine) GetBestMove() move.Move {
@@ -16,35 +29,48 @@ func (pvline *PVLine) GetBestMove() move.Move {
 }
 
 func (pvline *PVLine) GetBestMoveByPly(ply uint8) move.Move {
-	if uint8(len(pvline.moves)) <= ply {
+	if pvline.length <= int(ply) {
 		return move.NullMove
 	}
 	return pvline.moves[ply]
 }
 
-func (pvline *PVLine) Update(bestMove move.Move, newLine *PVLine) {
-	new := make([]move.Move, len(newLine.moves)+1)
-	new[0] = bestMove
-	copy(new[1:], newLine.moves)
-	pvline.moves = new
+// Len returns the number of moves of the line.
+func (pvline *PVLine) Len() int {
+	return pvline.length
 }
 
-func (pvline *PVLine) Reset() {
-	pvline.moves = nil
+// Moves returns the moves of the line, which must not be changed.
+func (pvline *PVLine) Moves() []move.Move {
+	return pvline.moves[:pvline.length]
 }
 
-func (pvline *PVLine) Copy() *PVLine {
-	r := PVLine{
-		moves: make([]move.Move, len(pvline.moves)),
+// Set replaces the line by the moves, which are truncated to MaxLength.
+func (pvline *PVLine) Set(moves []move.Move) {
+	pvline.length = copy(pvline.moves[:], moves)
+}
+
+// Append adds the move to the end of the line and returns false, if the line is full.
+func (pvline *PVLine) Append(m move.Move) bool {
+	if pvline.length == MaxLength {
+		return false
 	}
-	copy(r.moves, pvline.moves)
-	return &r
+	pvline.moves[pvline.length] = m
+	pvline.length++
+	return true
+}
+
+func (pvline *PVLine) Reset() {
+	pvline.length = 0
 }
 
 func (pvline PVLine) String() string {
-	pvStrings := make([]string, len(pvline.moves))
-	for i := range pvline.moves {
-		pvStrings[i] = fmt.Sprint(pvline.moves[i])
+	var sb strings.Builder
+	for i, m := range pvline.Moves() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(m.String())
 	}
-	return strings.Join(pvStrings, " ")
+	return sb.String()
 }
@@ -7,26 +7,39 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestPVLine_Update(t *testing.T) {
-	tests := []struct {
-		name    string
-		oldLine PVLine
-		move    move.Move
-		newLine PVLine
-		wanted  PVLine
-	}{
-		{
-			name:    "regular update",
-			oldLine: PVLine{[]move.Move{1, 2, 3}},
-			move:    4,
-			newLine: PVLine{[]move.Move{5, 6, 7}},
-			wanted:  PVLine{[]move.Move{4, 5, 6, 7}},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.oldLine.Update(tt.move, &tt.newLine)
-			assert.Equal(t, tt.wanted, tt.oldLine)
-		})
+func TestPVLine(t *testing.T) {
+	var m1, m2 move.Move
+	m1.SetSourceSquare(12).SetTargetSquare(28)
+	m2.SetSourceSquare(52).SetTargetSquare(36)
+
+	line := New(m1, m2)
+	assert.Equal(t, 2, line.Len())
+	assert.Equal(t, []move.Move{m1, m2}, line.Moves())
+	assert.Equal(t, m1, line.GetBestMove())
+	assert.Equal(t, m2, line.GetBestMoveByPly(1))
+	assert.Equal(t, move.NullMove, line.GetBestMoveByPly(2))
+	assert.Equal(t, "e2e4 e7e5", line.String())
+
+	// Copies are independent
+	other := line
+	other.Set([]move.Move{m2})
+	assert.Equal(t, "e2e4 e7e5", line.String())
+	assert.Equal(t, "e7e5", other.String())
+
+	line.Reset()
+	assert.Equal(t, "", line.String())
+	assert.Equal(t, move.NullMove, line.GetBestMove())
+}
+
+func TestPVLine_Append(t *testing.T) {
+	var line PVLine
+	for range MaxLength {
+		assert.True(t, line.Append(1))
 	}
+	assert.False(t, line.Append(1))
+	assert.Equal(t, MaxLength, line.Len())
+
+	// Lines are truncated to the maximal length
+	line = New(make([]move.Move, MaxLength+1)...)
+	assert.Equal(t, MaxLength, line.Len())
 }
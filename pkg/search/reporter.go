@@ -0,0 +1,112 @@
+package search
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+)
+
+// Reporter receives the events of the search, e.g. to print them in the UCI protocol.
+// Only the main thread reports events, so the methods are never called concurrently by one search.
+type Reporter interface {
+	// Iteration is called for every line finished in an iteration.
+	Iteration(IterationEvent)
+	// AspirationFail is called, if the score of an iteration is outside of the aspiration window
+	// and the iteration is searched again. The bound of the event is never Exact.
+	AspirationFail(IterationEvent)
+	// CurrentMove is called before a move at the root is searched.
+	CurrentMove(CurrentMoveEvent)
+	// BestMove is called once at the end of the search.
+	BestMove(BestMoveEvent)
+	// Message is called with additional information about the search, e.g. the calculated time limits.
+	Message(string)
+}
+
+// messageQueue collects the messages of other goroutines, e.g. the time limits calculated at a ponderhit,
+// so the main thread reports them and the reporter is never called concurrently.
+type messageQueue struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (q *messageQueue) push(msg string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, msg)
+}
+
+// pop returns and removes all messages.
+func (q *messageQueue) pop() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	messages := q.messages
+	q.messages = nil
+	return messages
+}
+
+// reportMessages reports the queued messages. It is only called by the main thread.
+func (s *Search) reportMessages() {
+	for _, msg := range s.messages.pop() {
+		s.Reporter.Message(msg)
+	}
+}
+
+// Bound describes, if the score is exact or only a bound of the real score.
+type Bound uint8
+
+const (
+	Exact Bound = iota
+	LowerBound
+	UpperBound
+)
+
+// WDL contains the probabilities to win, to draw and to lose in per mille.
+type WDL struct {
+	Win  int
+	Draw int
+	Loss int
+}
+
+// IterationEvent is the result of a line of an iteration.
+type IterationEvent struct {
+	MultiPV  int
+	Depth    uint8
+	SelDepth uint8
+	Score    int16
+	Bound    Bound
+	// WDL is only set, if requested by SearchParameter.ShowWDL.
+	WDL      *WDL
+	Time     time.Duration
+	Nodes    uint64
+	NPS      uint64
+	HashFull uint64
+	PV       pvline.PVLine
+}
+
+// CurrentMoveEvent is the move currently searched at the root.
+type CurrentMoveEvent struct {
+	Depth uint8
+	Move  move.Move
+	// Number starts with 1 for the first move searched.
+	Number int
+	// Time is the time since the start of the search.
+	Time time.Duration
+}
+
+// BestMoveEvent is the final result of the search.
+type BestMoveEvent struct {
+	Move move.Move
+	// Ponder is the expected answer of the opponent, which might be move.NullMove.
+	Ponder move.Move
+}
+
+// NoReporter ignores all events.
+type NoReporter struct{}
+
+func (NoReporter) Iteration(IterationEvent)      {}
+func (NoReporter) AspirationFail(IterationEvent) {}
+func (NoReporter) CurrentMove(CurrentMoveEvent)  {}
+func (NoReporter) BestMove(BestMoveEvent)        {}
+func (NoReporter) Message(string)                {}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingReporter records all events of a search.
+type recordingReporter struct {
+	iterations      []IterationEvent
+	aspirationFails []IterationEvent
+	currentMoves    []CurrentMoveEvent
+	bestMoves       []BestMoveEvent
+	messages        []string
+}
+
+func (r *recordingReporter) Iteration(e IterationEvent) { r.iterations = append(r.iterations, e) }
+func (r *recordingReporter) AspirationFail(e IterationEvent) {
+	r.aspirationFails = append(r.aspirationFails, e)
+}
+func (r *recordingReporter) CurrentMove(e CurrentMoveEvent) {
+	r.currentMoves = append(r.currentMoves, e)
+}
+func (r *recordingReporter) BestMove(e BestMoveEvent) { r.bestMoves = append(r.bestMoves, e) }
+func (r *recordingReporter) Message(msg string)       { r.messages = append(r.messages, msg) }
+
+func TestSearchReporter(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	r := &recordingReporter{}
+	s := NewSearch(*pos, DefaultTables)
+	s.Reporter = r
+	bestMove := s.Search(context.TODO(), SearchParameter{Depth: 4, Infinite: true, Threads: 2, ShowWDL: true})
+
+	// Every depth is reported once
+	require.Len(t, r.iterations, 4)
+	for i, e := range r.iterations {
+		assert.Equal(t, uint8(i+1), e.Depth)
+		assert.GreaterOrEqual(t, e.SelDepth, e.Depth)
+		assert.Equal(t, Exact, e.Bound)
+		assert.Equal(t, 1, e.MultiPV)
+		assert.NotNil(t, e.WDL)
+		assert.Positive(t, e.Nodes)
+	}
+	for _, e := range r.aspirationFails {
+		assert.NotEqual(t, Exact, e.Bound)
+	}
+
+	// Only the root moves of the main thread are reported
+	legal := len(pos.LegalMoves())
+	for _, e := range r.currentMoves {
+		assert.LessOrEqual(t, e.Number, legal)
+		assert.Contains(t, s.rootMoves, e.Move)
+	}
+	assert.NotEmpty(t, r.currentMoves)
+
+	require.Len(t, r.bestMoves, 1)
+	assert.Equal(t, bestMove, r.bestMoves[0].Move)
+	assert.Equal(t, s.PonderMove(), r.bestMoves[0].Ponder)
+}
+
+// exclusiveReporter records, if its methods are called concurrently.
+type exclusiveReporter struct {
+	recordingReporter
+	active     atomic.Int32
+	concurrent atomic.Bool
+}
+
+func (r *exclusiveReporter) enter() func() {
+	if r.active.Add(1) > 1 {
+		r.concurrent.Store(true)
+	}
+	// Give another goroutine the chance to call the reporter at the same time
+	time.Sleep(time.Millisecond)
+	return func() { r.active.Add(-1) }
+}
+
+func (r *exclusiveReporter) Iteration(e IterationEvent) {
+	defer r.enter()()
+	r.recordingReporter.Iteration(e)
+}
+
+func (r *exclusiveReporter) CurrentMove(e CurrentMoveEvent) {
+	defer r.enter()()
+	r.recordingReporter.CurrentMove(e)
+}
+
+func (r *exclusiveReporter) Message(msg string) {
+	defer r.enter()()
+	r.recordingReporter.Message(msg)
+}
+
+func TestSearchReporterPonderHit(t *testing.T) {
+	r := &exclusiveReporter{}
+	s := NewSearch(*position.New(), NewTables(1, 1))
+	s.Reporter = r
+	done := make(chan struct{})
+	go func() {
+		s.Search(context.TODO(), SearchParameter{MoveTime: 200, Ponder: true})
+		close(done)
+	}()
+
+	// The ponderhit arrives, while the main thread reports the search
+	time.Sleep(100 * time.Millisecond)
+	s.PonderHit()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("search did not return after the ponderhit")
+	}
+
+	assert.False(t, r.concurrent.Load())
+	assert.Contains(t, r.messages, "calculated time limits soft 200 hard 200")
+}
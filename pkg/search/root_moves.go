@@ -0,0 +1,52 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/shaardie/clemens/pkg/move"
+)
+
+// containsMove returns true, if the move is in the list, independent of the scores of the moves.
+func containsMove(moves []move.Move, m move.Move) bool {
+	for _, candidate := range moves {
+		if candidate.WithoutScore() == m.WithoutScore() {
+			return true
+		}
+	}
+	return false
+}
+
+// initRootMoves sets the moves to search at the root.
+// If searchMoves is not empty, the search is restricted to its legal moves.
+// Illegal search moves are reported and never widen the search to other moves,
+// so there is nothing to search, if none of them is legal.
+func (s *Search) initRootMoves(searchMoves []move.Move) {
+	legal := s.Pos.LegalMoves()
+	for _, m := range searchMoves {
+		if !containsMove(legal, m) {
+			s.Reporter.Message(fmt.Sprintf("search move %v is not legal", m))
+		}
+	}
+	s.rootMoves = make([]move.Move, 0, len(legal))
+	for _, m := range legal {
+		if len(searchMoves) == 0 || containsMove(searchMoves, m) {
+			s.rootMoves = append(s.rootMoves, m)
+		}
+	}
+	s.isRootRestricted = len(searchMoves) > 0 && len(s.rootMoves) < len(legal)
+}
+
+// hasRootMoves returns false, if the search is restricted to search moves, which are all illegal.
+func (s *Search) hasRootMoves() bool {
+	return !s.isRootRestricted || len(s.rootMoves) > 0
+}
+
+// isExcludedRootMove returns true, if the move should not be searched at the root,
+// e.g. because it is already the best move of another line in the MultiPV mode
+// or it is not part of the moves the search is restricted to.
+func (s *Search) isExcludedRootMove(m move.Move) bool {
+	if s.isRootRestricted && !containsMove(s.rootMoves, m) {
+		return true
+	}
+	return containsMove(s.excludedRootMoves, m)
+}
@@ -2,8 +2,12 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/shaardie/clemens/pkg/evaluation"
@@ -37,75 +41,300 @@ func init() {
 	}
 }
 
+var errNodeLimitReached = errors.New("node limit reached")
+
 const futility_pruning_depth uint8 = 5
 
 var futility_pruning_margin = [futility_pruning_depth]int16{0, 100, 150, 200, 250}
 
 const staticNullMovePruningMarging int16 = 75
 
+// singularExtensionDepth is the minimal depth for the singular extension search.
+const singularExtensionDepth uint8 = 6
+
+// singularExtensionMargin is the margin per depth, the transposition table move has to be better than all other moves.
+const singularExtensionMargin int16 = 2
+
+const (
+	// probCutDepth is the minimal depth for ProbCut.
+	probCutDepth uint8 = 5
+	// probCutReduction is the reduction of the shallow search of ProbCut.
+	probCutReduction uint8 = 4
+	// probCutMargin is the margin the shallow search has to beat beta.
+	probCutMargin int16 = 200
+)
+
+// futilityImprovingMargin is added to the futility margin, if the position is improving.
+const futilityImprovingMargin int16 = 50
+
 type Search struct {
-	ctx              context.Context
-	Pos              position.Position
-	nodes            uint64
-	PV               pvline.PVLine
+	ctx       context.Context
+	Pos       position.Position
+	nodes     atomic.Uint64
+	nodeLimit atomic.Uint64
+	// selDepth is the maximal ply reached in the current iteration including the quiescence search.
+	selDepth uint8
+	// ponderHit signals, that the opponent played the expected move while pondering.
+	ponderHit chan struct{}
+	PV        pvline.PVLine
+	// pv is the table of the principal variations of the current search path.
+	pv               pvTable
 	KillerMoves      [1024][2]move.Move
 	searchHistory    [1024]uint64
-	history          [types.COLOR_NUMBER][types.SQUARE_NUMBER][types.SQUARE_NUMBER]uint16
+	history          [types.COLOR_NUMBER][types.SQUARE_NUMBER][types.SQUARE_NUMBER]historyEntry
 	counter          [types.COLOR_NUMBER][types.SQUARE_NUMBER][types.SQUARE_NUMBER]move.Move
 	searchHistoryPly int
+	stack            searchStack
+
+	continuationHistory continuationHistory
+	captureHistory      captureHistory
+	correctionHistory   correctionHistory
+
+	// startPos and moves are the position set by SetPosition to detect, if a new position extends the current one.
+	startPos position.Position
+	moves    []string
+
+	// Lines contains the results for all lines of the last iteration in the MultiPV mode.
+	// The first line is always the principal variation.
+	Lines             []Info
+	multiPV           int
+	excludedRootMoves []move.Move
+	// completedLines are the lines of the last depth completed for all lines.
+	completedLines []Info
+
+	// rootMoves are the moves searched at the root.
+	// If the search is restricted, e.g. by the `go searchmoves` command, these are not all legal moves.
+	rootMoves        []move.Move
+	isRootRestricted bool
+
+	// mateSearch disables all pruning, which could hide a mate.
+	mateSearch bool
+	// disabledPruning are the optional pruning techniques not used by the search.
+	disabledPruning Pruning
+	// useCorrectionHistory corrects the static evaluations with the correction history.
+	useCorrectionHistory bool
+
+	// showWDL adds the probabilities to win, to draw and to lose to the info lines.
+	showWDL bool
+
+	// skill weakens the search. It is nil for the full strength.
+	skill *skill
+	// skillRNG chooses the moves of all weakened searches with the seed skillSeed.
+	skillRNG  *rand.Rand
+	skillSeed uint64
+	// skillTT is the transposition table of the weakened searches.
+	skillTT *transpositiontable.TranspositionTable
+
+	// tm decides when the search stops in time limited searches. It is nil for all other searches.
+	tm *timeManager
+
+	// helpers are the additional threads of the Lazy SMP,
+	// which are only used to fill the shared transposition table.
+	helpers []*Search
+
+	// tables are shared with the helpers.
+	tables Tables
+	// sharedTables are the tables the search was created with, which are used with the full strength.
+	sharedTables Tables
+
+	// Evaluator evaluates the positions. It defaults to the hand-crafted evaluation using the evaluation cache of the tables.
+	// The transposition table stores the static evaluations, so it has to be cleared, if the evaluator changes.
+	Evaluator Evaluator
+
+	// Reporter receives the events of the search. Helpers never report anything.
+	Reporter Reporter
+	// messages are reported by the main thread for other goroutines.
+	messages messageQueue
+	// start is the start time of the search.
+	start time.Time
 }
 
 type SearchParameter struct {
-	WTime     int
-	BTime     int
-	WInc      int
-	BInc      int
-	MovesToGo int
-	Depth     uint8
-	MoveTime  int
-	Infinite  bool
+	WTime       int
+	BTime       int
+	WInc        int
+	BInc        int
+	MovesToGo   int
+	Depth       uint8
+	MoveTime    int
+	Infinite    bool
+	Threads     int
+	MultiPV     int
+	SearchMoves []move.Move
+	Nodes       uint64
+	// Mate searches for a mate in the given number of moves
+	Mate int
+	// NodesTime is the number of nodes per millisecond used to convert the time into a node limit.
+	NodesTime int
+	// Ponder searches infinitely until the ponderhit and only then applies the time limits.
+	Ponder bool
+	// MoveOverhead is the time in milliseconds lost per move, e.g. for the communication with the GUI.
+	MoveOverhead int
+	// LimitStrength weakens the search to the SkillLevel using the SkillSeed for all random decisions.
+	LimitStrength bool
+	SkillLevel    int
+	SkillSeed     uint64
+	// ShowWDL adds the probabilities to win, to draw and to lose to the info lines.
+	ShowWDL bool
+	// DisabledPruning are the optional pruning techniques not used by the search.
+	DisabledPruning Pruning
+	// CorrectionHistory corrects the static evaluation by the errors typical for the pawn structure.
+	// It is disabled by default, since it is not verified by a SPRT yet.
+	CorrectionHistory bool
 }
 
 type Info struct {
-	Depth uint8
-	PV    pvline.PVLine
-	Score int16
+	Depth    uint8
+	SelDepth uint8
+	PV       pvline.PVLine
+	Score    int16
+	MultiPV  int
 }
 
 func (s *Search) bestMove() move.Move {
 	return s.PV.GetBestMove()
 }
 
-func NewSearch(pos position.Position) *Search {
+// Tables are the hash tables used by a search.
+// All threads of a search share the tables, so the tables are safe for concurrent use.
+type Tables struct {
+	TT        *transpositiontable.TranspositionTable
+	EvalCache *evaluation.Cache
+}
+
+// NewTables creates new and empty tables of the sizes in MB.
+func NewTables(ttSizeInMB, evalCacheSizeInMB int) Tables {
+	return Tables{
+		TT:        transpositiontable.New(ttSizeInMB),
+		EvalCache: evaluation.NewCache(evalCacheSizeInMB),
+	}
+}
+
+// DefaultTables are the tables for all searches, which do not need their own, e.g. the command line interface.
+var DefaultTables = Tables{
+	TT:        transpositiontable.Default,
+	EvalCache: evaluation.DefaultCache,
+}
+
+// NewSearch creates a search for the position, which uses the tables.
+func NewSearch(pos position.Position, tables Tables) *Search {
 	s := &Search{
-		Pos: pos,
+		Pos:          pos,
+		tables:       tables,
+		sharedTables: tables,
+		Evaluator:    evaluation.NewHandCrafted(tables.EvalCache),
+		ponderHit:    make(chan struct{}, 1),
+		Reporter:     NoReporter{},
 	}
+	s.reset(pos)
 	return s
 }
 
+// PonderMove returns the expected answer of the opponent to the best move.
+func (s *Search) PonderMove() move.Move {
+	return s.PV.GetBestMoveByPly(1)
+}
+
+// PonderHit signals, that the opponent played the expected move while pondering.
+// The search continues, but from now on, the time limits are applied.
+func (s *Search) PonderHit() {
+	select {
+	case s.ponderHit <- struct{}{}:
+	default:
+	}
+}
+
 func (s *Search) Search(ctx context.Context, sp SearchParameter) move.Move {
-	ctx, cancel := s.contextFromSearchParameter(ctx, sp)
+	ctx, cancel := context.WithCancel(ctx)
 	depth := max_depth
 	if sp.Depth > 0 {
 		depth = sp.Depth
 	}
 	s.ctx = ctx
-	s.SearchIterative(depth)
+
+	// The search might be reused for multiple searches
+	s.nodes.Store(0)
+
+	// A mate in N moves is found after at most 2N-1 plies
+	s.mateSearch = sp.Mate > 0
+	s.showWDL = sp.ShowWDL
+	s.disabledPruning = sp.DisabledPruning
+	s.useCorrectionHistory = sp.CorrectionHistory
+	if s.mateSearch {
+		depth = min(depth, uint8(min(2*sp.Mate-1, int(max_depth))))
+	}
+
+	// A weakened search is capped and uses multiple lines to choose a weaker move.
+	// It runs on a single thread to be reproducible.
+	s.initSkill(sp)
+	if s.skill != nil {
+		depth = min(depth, s.skill.maxDepth())
+		if sp.Nodes == 0 || sp.Nodes > s.skill.maxNodes() {
+			sp.Nodes = s.skill.maxNodes()
+		}
+		sp.MultiPV = max(sp.MultiPV, skillCandidates)
+		sp.Threads = 1
+	}
+
+	// We can not search more lines than there are moves at the root
+	s.initRootMoves(sp.SearchMoves)
+	if !s.hasRootMoves() {
+		s.Reporter.Message("no legal search move")
+		sp.Threads = 1
+	}
+	s.multiPV = max(min(sp.MultiPV, len(s.rootMoves)), 1)
+	s.Lines = make([]Info, s.multiPV)
+	s.completedLines = nil
+
+	// The entries of the previous searches are replaced first.
+	s.tables.TT.NewGeneration()
+
+	// The helpers are stopped as soon as the main thread is finished.
+	helperCtx, stopHelpers := context.WithCancel(ctx)
+	wg := s.startHelpers(helperCtx, sp.Threads-1, depth)
+
+	ponderHit := s.limitSearch(ctx, cancel, sp)
+	s.reportMessages()
+
+	if s.hasRootMoves() {
+		s.SearchIterative(depth)
+	}
+	if s.mateSearch && !s.isMateFound(sp.Mate) {
+		s.Reporter.Message(fmt.Sprintf("no mate in %v found", sp.Mate))
+	}
+	stopHelpers()
+	wg.Wait()
+
+	// While pondering, the best move must not be returned before the ponderhit or the stop
+	select {
+	case <-ponderHit:
+	case <-ctx.Done():
+	}
 	cancel()
 
+	s.reportMessages()
+
 	// We need at least a valid move
-	if s.bestMove() == move.NullMove {
+	if s.bestMove() == move.NullMove && s.hasRootMoves() {
 		s.ctx = context.TODO()
+		s.nodeLimit.Store(0)
 		s.SearchIterative(1)
 	}
+	if s.skill != nil {
+		s.chooseLine()
+	}
+	s.Reporter.BestMove(BestMoveEvent{Move: s.bestMove(), Ponder: s.PonderMove()})
 	return s.bestMove()
 }
 
 func (s *Search) SearchIterative(maxDepth uint8) {
-	start := time.Now()
+	s.start = time.Now()
+	s.searchIterative(1, maxDepth)
+}
+
+func (s *Search) searchIterative(depth, maxDepth uint8) {
 	alpha := -evaluation.INF
 	beta := evaluation.INF
-	var depth uint8 = 1
 	for depth <= maxDepth {
 		i, err := s.SearchRoot(depth, alpha, beta)
 		// Timeout
@@ -118,53 +347,125 @@ func (s *Search) SearchIterative(maxDepth uint8) {
 		// If the score is not in the last windows,
 		// re-run the search with the wider window, do not use the result and do not increase the depth.
 		if i.Score <= alpha || i.Score >= beta {
-			fmt.Printf("info string windows [%v,%v] too small for value %v. Re-run search.\n", alpha, beta, i.Score)
+			bound := UpperBound
+			if i.Score >= beta {
+				bound = LowerBound
+			}
+			s.reportMessages()
+			if s.skill == nil {
+				s.Reporter.AspirationFail(s.iterationEvent(i, bound))
+			}
 			alpha = -evaluation.INF
 			beta = evaluation.INF
 			continue
 		}
 
-		s.PV = *i.PV.Copy()
-		alpha = i.Score - widen_window
-		beta = i.Score + widen_window
+		s.PV = i.PV
+		// The window is limited to the range of the scores, since a mate score is close to the limits of an int16.
+		alpha = max(i.Score, -evaluation.INF+widen_window) - widen_window
+		beta = min(i.Score, evaluation.INF-widen_window) + widen_window
+		s.updateLine(i)
+
+		// In the MultiPV mode, search the other lines with the full window,
+		// while excluding the best moves of the lines already found.
+		for k := 2; k <= s.multiPV; k++ {
+			s.excludedRootMoves = append(s.excludedRootMoves, s.Lines[k-2].PV.GetBestMove())
+			i, err := s.SearchRoot(depth, -evaluation.INF, evaluation.INF)
+			if err != nil {
+				s.excludedRootMoves = nil
+				return
+			}
+			i.MultiPV = k
+			s.updateLine(i)
+		}
+		s.excludedRootMoves = nil
+		s.completedLines = slices.Clone(s.Lines)
 		depth++
 
-		// Print info
-		t := max(time.Since(start).Milliseconds(), 1) // should never be zero
-		fmt.Printf(
-			"info depth %v score cp %v time %v nodes %v nps %v hashfull %v pv %v\n",
-			i.Depth,
-			i.Score,
-			t,
-			s.nodes,
-			int64(s.nodes)*1000/t,
-			transpositiontable.HashFull(),
-			i.PV,
-		)
+		// In the mate search, we are done as soon as the mate is found
+		if s.mateSearch && s.isMateFound(int(maxDepth+1)/2) {
+			return
+		}
+
+		// Stop, if the time manager considers another iteration not worth it
+		if s.tm != nil && s.tm.shouldStop(s.PV.GetBestMove(), s.Lines[0].Score, s.totalNodes()) {
+			return
+		}
+	}
+}
+
+// isMateFound returns true, if the principal variation leads to a mate in at most the number of moves.
+// Only the main thread has lines, so the helpers never find a mate and run until they are stopped.
+func (s *Search) isMateFound(moves int) bool {
+	if len(s.Lines) == 0 {
+		return false
+	}
+	score := s.Lines[0].Score
+	return score > 0 && evaluation.IsCheckmateValue(score) && evaluation.MateIn(score) <= moves
+}
+
+// updateLine saves the result for a line and reports it.
+func (s *Search) updateLine(i Info) {
+	if i.MultiPV == 0 {
+		i.MultiPV = 1
+	}
+	if s.multiPV >= i.MultiPV {
+		s.Lines[i.MultiPV-1] = i
+	}
+	s.reportMessages()
+	// The lines of a weakened search are not reported, since only the chosen line is played.
+	if s.skill == nil {
+		s.Reporter.Iteration(s.iterationEvent(i, Exact))
+	}
+}
+
+// iterationEvent creates the event for the result of a line.
+func (s *Search) iterationEvent(i Info, b Bound) IterationEvent {
+	t := max(time.Since(s.start), time.Millisecond) // should never be zero
+	nodes := s.totalNodes()
+	e := IterationEvent{
+		MultiPV:  max(i.MultiPV, 1),
+		Depth:    i.Depth,
+		SelDepth: i.SelDepth,
+		Score:    i.Score,
+		Bound:    b,
+		Time:     t,
+		Nodes:    nodes,
+		NPS:      uint64(float64(nodes) / t.Seconds()),
+		HashFull: s.tables.TT.HashFull(),
+		PV:       i.PV,
+	}
+	if s.showWDL {
+		win, draw, loss := evaluation.WDL(&s.Pos, i.Score)
+		e.WDL = &WDL{Win: win, Draw: draw, Loss: loss}
 	}
+	return e
 }
 
 func (s *Search) SearchRoot(depth uint8, alpha, beta int16) (Info, error) {
-	s.KillerMoves = [1024][2]move.Move{}
+	s.selDepth = 0
 	pos := s.Pos
-	pvl := pvline.PVLine{}
-	score, err := s.negamax(&pos, alpha, beta, depth, 0, &pvl, true, move.NullMove)
+	score, err := s.negamax(&pos, alpha, beta, depth, 0, true)
 	if err != nil {
 		return Info{}, err
 	}
-	return Info{
-		Depth: depth,
-		Score: score,
-		PV:    *pvl.Copy(),
-	}, nil
+	i := Info{
+		Depth:    depth,
+		SelDepth: s.selDepth,
+		Score:    score,
+	}
+	i.PV.Set(s.pv.line(0))
+	s.extendPV(&i.PV, depth)
+	return i, nil
 }
 
-func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply uint8, pvl *pvline.PVLine, canNull bool, previousMove move.Move) (int16, error) {
-	// value to info channel and check if we are done
-	select {
-	case <-s.ctx.Done():
-		return 0, s.ctx.Err()
-	default:
+func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply uint8, canNull bool) (int16, error) {
+	// The principal variation of the node is empty until a move raises alpha.
+	s.pv.reset(ply)
+
+	// check if we are done
+	if err := s.stop(); err != nil {
+		return 0, err
 	}
 
 	isRoot := ply == 0
@@ -180,49 +481,118 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 	// Evaluate the leaf node
 	if depth <= 0 {
-		return s.quiescence(pos, alpha, beta, ply)
+		return s.quiescence(pos, alpha, beta, ply, s.isEnabled(QuiescenceChecks))
 	}
-	s.nodes++
-
-	// Check if the position is a repetition.
-	// On the first repetitions we return our contempt value.
-	// https://www.chessprogramming.org/Repetitions
-	if !isRoot && !isInCheck && s.isRepetition(pos) {
-		return evaluation.Contempt(pos), nil
+	s.nodes.Add(1)
+	s.selDepth = max(s.selDepth, ply)
+
+	// The excluded move is not searched, so the result is not the result of the position.
+	// The position is searched again by the singular extension search, so it is already in the history.
+	excludedMove := s.stack[ply].excludedMove
+
+	if excludedMove == move.NullMove {
+		// Check if the position is a repetition.
+		// On the first repetitions we return our contempt value.
+		// https://www.chessprogramming.org/Repetitions
+		if !isRoot && !isInCheck && s.isRepetition(pos) {
+			return evaluation.Contempt(pos), nil
+		}
+		s.pushHistory(pos)
+		defer s.popHistory()
 	}
-	s.pushHistory(pos)
-	defer s.popHistory()
 
 	pvMove := s.PV.GetBestMoveByPly(ply)
 
 	// Check if we can use the transition table but not on root
-	score, use, ttMove := transpositiontable.Get(pos.ZobristHash, alpha, beta, depth, ply)
-	if !isRoot && !pvNode && use {
-		return score, nil
+	ttEntry, ttHit := s.tables.TT.Probe(pos.ZobristHash, ply)
+	ttMove := ttEntry.BestMove
+	if !isRoot && !pvNode && ttHit && excludedMove == move.NullMove {
+		if score, use := ttEntry.Cutoff(alpha, beta, depth); use {
+			return score, nil
+		}
+	}
+
+	// The static evaluation is only calculated once per node and stored in the stack,
+	// so it can be compared with the evaluation of the previous plies.
+	ss := &s.stack[ply]
+	ss.inCheck = isInCheck
+	ss.currentMove = move.NullMove
+	// The raw evaluation is kept to update the correction history.
+	// It is stored in the transposition table, so it does not have to be calculated again.
+	rawEval, staticEval := transpositiontable.NoStaticEval, int16(0)
+	if !isInCheck {
+		rawEval = ttEntry.StaticEval
+		if !ttHit || rawEval == transpositiontable.NoStaticEval {
+			rawEval = s.evaluate(pos)
+		}
+		staticEval = rawEval
+		if s.useCorrectionHistory {
+			staticEval = s.correctEvaluation(pos, rawEval)
+		}
+	}
+	ss.staticEval = staticEval
+	improving := s.stack.improving(ply)
+
+	// Internal Iterative Reduction
+	// Without a transposition table move the move ordering is worse and the node is probably less important,
+	// so it is searched with less depth.
+	if s.isEnabled(InternalIterativeReduction) &&
+		!s.mateSearch &&
+		!isRoot &&
+		depth >= internalIterativeReductionDepth &&
+		excludedMove == move.NullMove &&
+		ttMove == move.NullMove {
+		depth--
+	}
+
+	// Razoring
+	// If the static evaluation is far below alpha, only the captures in the quiescence search might raise it.
+	if s.isEnabled(Razoring) &&
+		!s.mateSearch &&
+		!pvNode &&
+		!isInCheck &&
+		excludedMove == move.NullMove &&
+		depth <= razoringDepth &&
+		!evaluation.IsCheckmateValue(alpha) &&
+		staticEval+razoringMargin[depth] < alpha {
+		score, err := s.quiescence(pos, alpha-1, alpha, ply, s.isEnabled(QuiescenceChecks))
+		if err != nil {
+			return 0, err
+		}
+		if score < alpha {
+			return score, nil
+		}
 	}
 
 	// Static Null Move Pruning
-	if !isInCheck && !pvNode && !evaluation.IsCheckmateValue(beta) {
+	// The margin is smaller, if the position is improving.
+	if !s.mateSearch && !isInCheck && !pvNode && excludedMove == move.NullMove && !evaluation.IsCheckmateValue(beta) {
+		snmpDepth := int16(depth)
+		if improving {
+			snmpDepth--
+		}
 		// score - margin as potential new beta
-		b := evaluation.Evaluation(pos) - staticNullMovePruningMarging*int16(depth)
+		b := staticEval - staticNullMovePruningMarging*snmpDepth
 		if b >= beta {
 			return b, nil
 		}
 	}
 
-	potentialPVLine := pvline.PVLine{}
-
 	// Null Move Pruning
 	// https://www.chessprogramming.org/Null_Move_Pruning
-	if depth > 2 && canNull && !isInCheck && !pvNode && !evaluation.IsPawnEndgame(pos) && evaluation.Evaluation(pos) > beta {
+	// The null move is reduced more, if the position is improving.
+	if !s.mateSearch && depth > 2 && canNull && excludedMove == move.NullMove && !isInCheck && !pvNode && !s.Evaluator.IsPawnEndgame(pos) && staticEval > beta {
+		ss.currentMove = move.NullMove
 		ep := pos.MakeNullMove()
 		var R uint8 = 2
 		if depth > 6 {
 			R = 3
 		}
-		score, err := s.negamax(pos, -beta, -beta+1, depth-R-1, ply+1, &potentialPVLine, false, move.NullMove)
+		if improving && depth > R+2 {
+			R++
+		}
+		score, err := s.negamax(pos, -beta, -beta+1, depth-R-1, ply+1, false)
 		pos.UnMakeNullMove(ep)
-		potentialPVLine.Reset()
 		if err != nil {
 			return 0, err
 		}
@@ -232,30 +602,138 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 		}
 	}
 
+	// ProbCut
+	// https://www.chessprogramming.org/ProbCut
+	// If a good capture beats beta by a margin in a shallow search, the full search is likely to fail high as well.
+	// The margin is added without overflow, since beta might be close to the limits of the scores.
+	probCutBeta := int16(min(int(beta)+int(probCutMargin), int(evaluation.INF)))
+	if !s.mateSearch &&
+		!pvNode &&
+		depth >= probCutDepth &&
+		excludedMove == move.NullMove &&
+		!isInCheck &&
+		!evaluation.IsCheckmateValue(beta) &&
+		!evaluation.IsCheckmateValue(probCutBeta) &&
+		// Skip, if the transposition table already knows, that the shallow search does not beat beta
+		!(ttHit && ttEntry.Depth+probCutReduction >= depth && ttEntry.Score < probCutBeta) {
+
+		captures := move.NewMoveList()
+		pos.GeneratePseudoLegalCaptures(captures)
+		s.scoreMoves(pos, captures, move.NullMove, ttMove, move.NullMove, ply)
+		for i := range captures.Length() {
+			captures.SortIndex(i)
+			m := captures.Get(i)
+			// Only captures, which are able to beat beta by the margin on their own
+			if !evaluation.StaticExchangeEvaluationAtLeast(pos, m, probCutBeta-staticEval) {
+				continue
+			}
+			prevPos := *pos
+			pos.MakeMove(*m)
+			if !pos.IsLegal() {
+				*pos = prevPos
+				continue
+			}
+			ss.currentMove = m.WithoutScore()
+			ss.movedPiece = prevPos.PiecesBoard[m.GetSourceSquare()]
+
+			// Verify with the quiescence search first, before the more expensive shallow search
+			score, err := s.quiescence(pos, -probCutBeta, -probCutBeta+1, ply+1, s.isEnabled(QuiescenceChecks))
+			if err == nil && -score >= probCutBeta {
+				score, err = s.negamax(pos, -probCutBeta, -probCutBeta+1, depth-probCutReduction, ply+1, true)
+			}
+			*pos = prevPos
+			if err != nil {
+				return 0, err
+			}
+			score = -score
+			if score >= probCutBeta {
+				s.tables.TT.PotentiallySave(pos.ZobristHash, m.WithoutScore(), depth-probCutReduction+1, ply, score, s.storedEval(rawEval), transpositiontable.BetaNode)
+				return score, nil
+			}
+		}
+		ss.currentMove = move.NullMove
+	}
+
 	// Check if we can use Futility Pruning
-	fPrune := !pvNode &&
+	// The margin is larger, if the position is improving.
+	futilityMargin := int16(0)
+	if depth < futility_pruning_depth {
+		futilityMargin = futility_pruning_margin[depth]
+		if improving {
+			futilityMargin += futilityImprovingMargin
+		}
+	}
+	fPrune := !s.mateSearch &&
+		!pvNode &&
 		depth < futility_pruning_depth &&
 		!isInCheck &&
 		!evaluation.IsCheckmateValue(alpha) &&
 		!evaluation.IsCheckmateValue(beta) &&
-		evaluation.Evaluation(pos)+futility_pruning_margin[depth] <= alpha
+		staticEval+futilityMargin <= alpha
+
+	// Singular Extensions and Multi-Cut
+	// https://www.chessprogramming.org/Singular_Extensions
+	// If the transposition table move is much better than all other moves, it is extended.
+	// If other moves also beat beta, the node is likely to fail high and is cut.
+	var singularExtension uint8
+	if !s.mateSearch &&
+		!isRoot &&
+		ply < max_depth &&
+		depth >= singularExtensionDepth &&
+		excludedMove == move.NullMove &&
+		ttHit &&
+		ttMove != move.NullMove &&
+		ttEntry.IsLowerBound() &&
+		ttEntry.Depth+3 >= depth &&
+		!evaluation.IsCheckmateValue(ttEntry.Score) {
+
+		singularBeta := ttEntry.Score - singularExtensionMargin*int16(depth)
+		ss.excludedMove = ttMove.WithoutScore()
+		score, err := s.negamax(pos, singularBeta-1, singularBeta, (depth-1)/2, ply, false)
+		ss.excludedMove = move.NullMove
+		if err != nil {
+			return 0, err
+		}
+		if score < singularBeta {
+			singularExtension = 1
+		} else if singularBeta >= beta {
+			return singularBeta, nil
+		}
+	}
+
+	// The singular extension search used the same ply, so the principal variation is cleared again.
+	s.pv.reset(ply)
 
 	var prevPos position.Position
 	var bestMove move.Move
 	var bestScore int16 = -evaluation.INF
 	var legalMoves uint8
+	var score int16
 	var err error
 	nodeType := transpositiontable.AlphaNode
 
+	// The moves searched without a cutoff get a malus in the history on a cutoff
+	var quietsSearched, capturesSearched [maxQuietsSearched]move.Move
+	var numberOfQuiets, numberOfCaptures int
+	// quietMoves is the number of legal quiet moves including the skipped ones
+	var quietMoves int
+
 	// Generate all moves and order them
 	moves := move.NewMoveList()
 	pos.GeneratePseudoLegalMoves(moves)
+	previousMove := s.stack.previousMove(ply)
 	s.scoreMoves(pos, moves, pvMove, ttMove, previousMove, ply)
 	// s.orderMoves(pos, moves, pvMove, ttMove, ply)
 
 	for i := range moves.Length() {
 		moves.SortIndex(i)
 		m := moves.Get(i)
+		if isRoot && s.isExcludedRootMove(*m) {
+			continue
+		}
+		if m.WithoutScore() == excludedMove {
+			continue
+		}
 		prevPos = *pos
 		pos.MakeMove(*m)
 		if !pos.IsLegal() {
@@ -263,18 +741,64 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 			continue
 		}
 		legalMoves++
+		ss.currentMove = m.WithoutScore()
+		ss.movedPiece = prevPos.PiecesBoard[m.GetSourceSquare()]
+		if isRoot {
+			s.reportMessages()
+			s.Reporter.CurrentMove(CurrentMoveEvent{
+				Depth:  depth,
+				Move:   m.WithoutScore(),
+				Number: int(legalMoves),
+				Time:   time.Since(s.start),
+			})
+		}
 
-		// Fulility Pruning
-		if fPrune && !prevPos.IsCapture(*m) && m.GetMoveType() != move.PROMOTION && !pos.IsInCheck(pos.SideToMove) {
-			*pos = prevPos
-			continue
+		isCapture := prevPos.IsCapture(*m)
+		isPromotion := m.GetMoveType() == move.PROMOTION
+		givesCheck := pos.IsInCheck(pos.SideToMove)
+		if !isCapture {
+			quietMoves++
+		}
+
+		// Skip moves at shallow depth, which are unlikely to raise alpha.
+		// Moves are only skipped after a move is searched, so the node has a score.
+		if !s.mateSearch && !isRoot && !givesCheck && !isPromotion && canPrune(bestScore) {
+			// Futility Pruning
+			if fPrune && !isCapture {
+				*pos = prevPos
+				continue
+			}
+
+			// Late Move Pruning
+			if s.isEnabled(LateMovePruning) &&
+				!isCapture &&
+				!isInCheck &&
+				depth <= lateMovePruningDepth &&
+				quietMoves > lateMovePruningThreshold(depth, improving) {
+				*pos = prevPos
+				continue
+			}
+
+			// Static Exchange Evaluation Pruning
+			if s.isEnabled(SEEPruning) &&
+				depth <= seePruningDepth &&
+				!evaluation.StaticExchangeEvaluationAtLeast(&prevPos, m, seePruningThreshold(depth, isCapture)) {
+				*pos = prevPos
+				continue
+			}
+		}
+
+		// Extend the singular move
+		newDepth := depth - 1
+		if m.WithoutScore() == ttMove.WithoutScore() {
+			newDepth += singularExtension
 		}
 
 		// Principal Variation Search with LMR
 		if legalMoves == 1 {
 			// First Move
 			// always with full depth
-			score, err = s.negamax(pos, -beta, -alpha, depth-1, ply+1, &potentialPVLine, true, *m)
+			score, err = s.negamax(pos, -beta, -alpha, newDepth, ply+1, true)
 			if err != nil {
 				return 0, err
 			}
@@ -283,12 +807,9 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 			// Late Move Reductions
 			reduction := uint8(0)
 
-			isCapture := prevPos.IsCapture(*m)
-			isPromotion := m.GetMoveType() == move.PROMOTION
-			givesCheck := pos.IsInCheck(pos.SideToMove)
-
 			// Reduce only quite moves
-			if depth >= 3 &&
+			if !s.mateSearch &&
+				depth >= 3 &&
 				legalMoves > 3 &&
 				!isCapture &&
 				!isPromotion &&
@@ -296,8 +817,13 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 				reduction = lmrTable[min(depth, 63)][min(legalMoves, 63)]
 
+				// Reduce more, if the position is not improving
+				if !improving {
+					reduction++
+				}
+
 				// Reduce less for killer moves
-				if *m == s.KillerMoves[ply][0] || *m == s.KillerMoves[ply][1] {
+				if m.WithoutScore() == s.KillerMoves[ply][0] || m.WithoutScore() == s.KillerMoves[ply][1] {
 					if reduction > 0 {
 						reduction--
 					}
@@ -311,7 +837,7 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 			// Search with reduced depth (or with regular depth, if reduction==1)
 			score, err = s.negamax(pos, -alpha-1, -alpha,
-				depth-1-reduction, ply+1, &pvline.PVLine{}, true, *m)
+				newDepth-reduction, ply+1, true)
 			if err != nil {
 				return 0, err
 			}
@@ -319,7 +845,7 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 			// If reduced and score > alpha, re-research with full depth and null window
 			if reduction > 0 && score > alpha {
-				score, err = s.negamax(pos, -alpha-1, -alpha, depth-1, ply+1, &pvline.PVLine{}, true, *m)
+				score, err = s.negamax(pos, -alpha-1, -alpha, newDepth, ply+1, true)
 				if err != nil {
 					return 0, err
 				}
@@ -328,7 +854,7 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 			// If score > alpha search, re-research with full depth
 			if score > alpha {
-				score, err = s.negamax(pos, -beta, -alpha, depth-1, ply+1, &potentialPVLine, true, *m)
+				score, err = s.negamax(pos, -beta, -alpha, newDepth, ply+1, true)
 				if err != nil {
 					return 0, err
 				}
@@ -340,50 +866,39 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 
 		if score > bestScore {
 			bestScore = score
-			bestMove = *m
-		}
-
-		if score >= beta {
-			nodeType = transpositiontable.BetaNode
-			if pos.GetPiece(m.GetTargetSquare()) == types.NO_PIECE && m.GetMoveType() != move.EN_PASSANT {
-				// Update Killer Move, if quiet move
-				if s.KillerMoves[ply][0] != bestMove {
-					s.KillerMoves[ply][1] = s.KillerMoves[ply][0]
-				}
-				s.KillerMoves[ply][0] = bestMove
-
-				// Remember move for history heuristic
-				sourceSquare := m.GetSourceSquare()
-				targetSquare := m.GetTargetSquare()
-				s.history[pos.SideToMove][sourceSquare][targetSquare] += uint16(depth) * uint16(depth)
-				if s.history[pos.SideToMove][m.GetSourceSquare()][m.GetTargetSquare()] > killerMoveScore-2 {
-					for i := range types.SQUARE_NUMBER {
-						for j := range types.SQUARE_NUMBER {
-							s.history[pos.SideToMove][i][j] /= 2
-						}
-					}
-				}
-
-				// Update counter moves
-				if previousMove != move.NullMove {
-					s.counter[pos.SideToMove][previousMove.GetSourceSquare()][previousMove.GetTargetSquare()] = *m
-				}
-			}
-			break
+			bestMove = m.WithoutScore()
 		}
 
+		// Update the principal variation also on a cutoff, so it is available for scores outside of the window.
 		if score > alpha {
 			nodeType = transpositiontable.PVNode
 			alpha = score
+			s.pv.update(ply, bestMove)
+		}
 
-			pvl.Update(bestMove, &potentialPVLine)
+		if score >= beta {
+			nodeType = transpositiontable.BetaNode
+			s.updateHistories(pos, bestMove, ply, depth, quietsSearched[:numberOfQuiets], capturesSearched[:numberOfCaptures])
+			break
+		}
 
+		if pos.IsCapture(*m) {
+			if numberOfCaptures < maxQuietsSearched {
+				capturesSearched[numberOfCaptures] = m.WithoutScore()
+				numberOfCaptures++
+			}
+		} else if numberOfQuiets < maxQuietsSearched {
+			quietsSearched[numberOfQuiets] = m.WithoutScore()
+			numberOfQuiets++
 		}
-		potentialPVLine.Reset()
 	}
 
 	// There are no legal moves, so it is either a checkmate or a stalemate
 	if legalMoves == 0 {
+		// The excluded move is the only legal move
+		if excludedMove != move.NullMove {
+			return alpha, nil
+		}
 		// Checkmate, set lowest possible value, but increase by the number of plys,
 		// so the engine is looking for shorter mates.
 		if isInCheck {
@@ -393,129 +908,214 @@ func (s *Search) negamax(pos *position.Position, alpha, beta int16, depth, ply u
 		return evaluation.Contempt(pos), nil
 	}
 
-	select {
-	case <-s.ctx.Done():
-		return 0, s.ctx.Err()
-	default:
-		transpositiontable.PotentiallySave(pos.ZobristHash, bestMove, depth, bestScore, nodeType, s.Pos.HalfMoveClock)
+	// The result of a root with excluded moves is not the result of the position
+	if excludedMove != move.NullMove || isRoot && (s.isRootRestricted || len(s.excludedRootMoves) > 0) {
+		return bestScore, nil
 	}
+
+	if err := s.stop(); err != nil {
+		return 0, err
+	}
+
+	// Update the correction history, if the score is not a bound on the wrong side of the static evaluation.
+	// Captures are excluded, since their score is not related to the static evaluation of the position.
+	if s.useCorrectionHistory &&
+		!isInCheck &&
+		!evaluation.IsCheckmateValue(bestScore) &&
+		(bestMove == move.NullMove || !pos.IsCapture(bestMove)) &&
+		!(nodeType == transpositiontable.BetaNode && bestScore <= staticEval) &&
+		!(nodeType == transpositiontable.AlphaNode && bestScore >= staticEval) {
+		s.updateCorrectionHistory(pos, depth, rawEval, bestScore)
+	}
+
+	s.tables.TT.PotentiallySave(pos.ZobristHash, bestMove, depth, ply, bestScore, s.storedEval(rawEval), nodeType)
 	return bestScore, nil
 }
 
-func (s *Search) quiescence(pos *position.Position, alpha, beta int16, ply uint8) (int16, error) {
-	s.nodes++
-	// value to info channel and check if we are done
+// stop returns an error, if the search has to be stopped,
+// because the context is done or the node limit is reached.
+func (s *Search) stop() error {
 	select {
 	case <-s.ctx.Done():
-		return 0, s.ctx.Err()
+		return s.ctx.Err()
 	default:
 	}
-
-	stand_pat := evaluation.Evaluation(pos)
-	if stand_pat >= beta {
-		return beta, nil
+	if limit := s.nodeLimit.Load(); limit > 0 && s.totalNodes() >= limit {
+		return errNodeLimitReached
 	}
-	if alpha < stand_pat {
-		alpha = stand_pat
+	return nil
+}
+
+// quiescence searches the captures until the position is quiet, so the static evaluation is reliable.
+// If in check, all evasions are searched, since the side to move is not able to stand pat.
+// With quietChecks, quiet moves giving check are searched as well, which is only done at the first ply of the quiescence search.
+// https://www.chessprogramming.org/Quiescence_Search
+func (s *Search) quiescence(pos *position.Position, alpha, beta int16, ply uint8, quietChecks bool) (int16, error) {
+	s.nodes.Add(1)
+	s.selDepth = max(s.selDepth, ply)
+	// check if we are done
+	if err := s.stop(); err != nil {
+		return 0, err
 	}
 
+	isInCheck := pos.IsInCheck(pos.SideToMove)
+
 	// Hard limit
 	if ply == quiescence_max_depth {
-		return alpha, nil
+		return s.evaluate(pos), nil
+	}
+
+	// Transposition Table
+	// The entries of the quiescence search have their own depth, so they are never used by the main search.
+	ttEntry, ttHit := s.tables.TT.Probe(pos.ZobristHash, ply)
+	if ttHit {
+		if score, use := ttEntry.Cutoff(alpha, beta, transpositiontable.QuiescenceDepth); use {
+			return score, nil
+		}
+	}
+
+	// Stand pat, if not in check
+	originalAlpha := alpha
+	stand_pat := transpositiontable.NoStaticEval
+	if !isInCheck {
+		stand_pat = ttEntry.StaticEval
+		if !ttHit || stand_pat == transpositiontable.NoStaticEval {
+			stand_pat = s.evaluate(pos)
+		}
+		if stand_pat >= beta {
+			s.tables.TT.PotentiallySave(pos.ZobristHash, move.NullMove, transpositiontable.QuiescenceDepth, ply, beta, s.storedEval(stand_pat), transpositiontable.BetaNode)
+			return beta, nil
+		}
+		if alpha < stand_pat {
+			alpha = stand_pat
+		}
 	}
 
 	var prevPos position.Position
+	ss := &s.stack[ply]
+	bestMove := move.NullMove
+	legalMoves := 0
 
-	// Generate all captures and order them
+	// Generate all evasions, all captures and quiet checks or only the captures and order them
 	moves := move.NewMoveList()
-	pos.GeneratePseudoLegalCaptures(moves)
-	// s.orderMoves(pos, moves, move.NullMove, move.NullMove, ply)
-	s.scoreMoves(pos, moves, move.NullMove, move.NullMove, move.NullMove, ply)
+	if isInCheck || quietChecks {
+		pos.GeneratePseudoLegalMoves(moves)
+	} else {
+		pos.GeneratePseudoLegalCaptures(moves)
+	}
+	s.scoreMoves(pos, moves, move.NullMove, ttEntry.BestMove, s.stack.previousMove(ply), ply)
 	for i := range moves.Length() {
 		moves.SortIndex(i)
 		m := moves.Get(i)
+		isCapture := pos.IsCapture(*m)
+
+		// Quiet moves are only searched, if they are evasions or give check
+		if !isInCheck && !isCapture && !pos.GivesCheck(*m) {
+			continue
+		}
 
-		// Delta Pruning, https://www.chessprogramming.org/Delta_Pruning
-		// If the current capture plus some safety margin is not able to raise alpha, we can skip the move.
-		if m.GetMoveType() != move.EN_PASSANT {
-			// Safety margin of 2 centipawns
-			margin := 2 * evaluation.PieceValue[types.PAWN]
-			// Take promotion into account.
-			if m.GetMoveType() == move.PROMOTION {
-				margin = margin - evaluation.PieceValue[types.PAWN] + evaluation.PieceValue[m.GetPromitionPieceType()]
+		// Every evasion is searched
+		if !isInCheck {
+			// Delta Pruning, https://www.chessprogramming.org/Delta_Pruning
+			// If the current capture plus some safety margin is not able to raise alpha, we can skip the move.
+			if isCapture && m.GetMoveType() != move.EN_PASSANT {
+				// Safety margin of 2 centipawns
+				margin := 2 * s.Evaluator.PieceValue(types.PAWN)
+				// Take promotion into account.
+				if m.GetMoveType() == move.PROMOTION {
+					margin = margin - s.Evaluator.PieceValue(types.PAWN) + s.Evaluator.PieceValue(m.GetPromitionPieceType())
+				}
+				// Skip Delta Pruning in the endgame to not become blind against insufficient material.
+				if stand_pat+s.Evaluator.PieceValue(pos.PiecesBoard[m.GetTargetSquare()].Type())+margin < alpha && !s.Evaluator.IsEndgame(pos) {
+					continue
+				}
 			}
-			// Skip Delta Pruning in the endgame to not become blind against insufficient material.
-			if stand_pat+evaluation.PieceValue[pos.PiecesBoard[m.GetTargetSquare()].Type()]+margin < alpha && !evaluation.IsEndgame(pos) {
+
+			// If the static exchange of pieces on the target square does not gain any positive material value,
+			// we can ignore this move completely. This also skips quiet checks, which lose the moved piece.
+			// En Passants are excluded because the target square of the pawn is not the square of the capture.
+			if m.GetMoveType() != move.EN_PASSANT && !evaluation.StaticExchangeEvaluationAtLeast(pos, m, 0) {
 				continue
 			}
 		}
 
-		// If the static exchange of pieces on the target square does not gain any positive material value,
-		// we can ignore this move completely.
-		// En Passants are excluded because the target square of the pawn is not the square of the capture.
-		if m.GetMoveType() != move.EN_PASSANT && evaluation.StaticExchangeEvaluation(pos, m) < 0 {
-			continue
-		}
-
 		prevPos = *pos
 		pos.MakeMove(*m)
 		if !pos.IsLegal() {
 			*pos = prevPos
 			continue
 		}
-		score, err := s.quiescence(pos, -beta, -alpha, ply+1)
+		legalMoves++
+
+		ss.currentMove = m.WithoutScore()
+		ss.movedPiece = prevPos.PiecesBoard[m.GetSourceSquare()]
+		score, err := s.quiescence(pos, -beta, -alpha, ply+1, false)
 		*pos = prevPos
 		if err != nil {
 			return 0, err
 		}
 		score = -score
 		if score >= beta {
+			s.tables.TT.PotentiallySave(pos.ZobristHash, m.WithoutScore(), transpositiontable.QuiescenceDepth, ply, beta, s.storedEval(stand_pat), transpositiontable.BetaNode)
 			return beta, nil
 		}
 
 		if score > alpha {
 			alpha = score
+			bestMove = m.WithoutScore()
 		}
 	}
 
+	// Checkmate, since every evasion is generated
+	if isInCheck && legalMoves == 0 {
+		return -evaluation.INF + int16(ply), nil
+	}
+
+	nodeType := transpositiontable.AlphaNode
+	if alpha > originalAlpha {
+		nodeType = transpositiontable.PVNode
+	}
+	s.tables.TT.PotentiallySave(pos.ZobristHash, bestMove, transpositiontable.QuiescenceDepth, ply, alpha, s.storedEval(stand_pat), nodeType)
 	return alpha, nil
 }
 
-func (s *Search) contextFromSearchParameter(ctx context.Context, sp SearchParameter) (context.Context, context.CancelFunc) {
+// limitSearch limits the search according to the search parameter,
+// either by cancelling the search after the calculated time or by setting the node limit.
+// While pondering, the time limits are only applied after the ponderhit,
+// which is signaled by closing the returned channel.
+func (s *Search) limitSearch(ctx context.Context, cancel context.CancelFunc, sp SearchParameter) <-chan struct{} {
+	s.nodeLimit.Store(sp.Nodes)
+	s.tm = nil
+	ponderHit := make(chan struct{})
+
 	// No need for any timeout
 	if sp.Infinite {
-		return ctx, func() {}
+		close(ponderHit)
+		return ponderHit
 	}
-	movetime := calculateTime(s.Pos.SideToMove, s.searchHistoryPly, sp)
-	fmt.Printf("info string calculated timeout %v\n", movetime)
-	return context.WithTimeout(ctx, time.Duration(movetime)*time.Millisecond)
-}
 
-func calculateTime(sideToMove types.Color, plys int, sp SearchParameter) int {
-	var t, inc int
-	if sideToMove == types.BLACK {
-		t = sp.BTime
-		inc = sp.BInc
-	} else {
-		t = sp.WTime
-		inc = sp.WInc
+	// A pure node limited search should be reproducible and a mate search should run until the mate is found,
+	// so both are not stopped by a timeout.
+	if (sp.Nodes > 0 || sp.Mate > 0) && sp.WTime == 0 && sp.BTime == 0 && sp.MoveTime == 0 {
+		close(ponderHit)
+		return ponderHit
 	}
 
-	// calculate for 60 moves or at least 20 remaining
-	remainingMoves := max(60-plys/2, 20)
-
-	var movetime int
-	if sp.MoveTime > 0 {
-		movetime = sp.MoveTime
-	} else if t > 0 {
-		// calculate reasonable time, there is possibly a better way
-		movetime = (t + inc*remainingMoves) / remainingMoves
-		// do not calculate too long
-		movetime = min(movetime, maxTimeInMs)
-	} else { // I do not know, calcuate a second
-		movetime = 1000
+	// The time is calculated now, since the search history changes during the search.
+	soft, hard := calculateTimeLimits(s.Pos.SideToMove, s.searchHistoryPly, sp)
+	s.tm = newTimeManager(soft, hard, sp.MoveTime > 0, sp.NodesTime)
+	if !sp.Ponder {
+		s.startTime(cancel)
+		close(ponderHit)
+		return ponderHit
 	}
-
-	// Current puffer of 10% for teardown process, but at least 50milliseconds
-	return movetime - max(movetime/10, 50)
+	go func() {
+		select {
+		case <-s.ponderHit:
+			s.startTime(cancel)
+			close(ponderHit)
+		case <-ctx.Done():
+		}
+	}()
+	return ponderHit
 }
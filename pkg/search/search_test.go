@@ -4,9 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
 	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
 	"github.com/shaardie/clemens/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,8 +25,23 @@ func BenchmarkSearchKiwipete(b *testing.B) {
 
 	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
 	require.NoError(b, err)
-	s := NewSearch(*pos)
-	s.Search(context.TODO(), SearchParameter{Depth: 7, Infinite: true})
+
+	// The speed-up of the Lazy SMP is the ratio of the time per operation,
+	// since all searches have to reach the same depth.
+	for _, threads := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("threads-%v", threads), func(b *testing.B) {
+			var nodes uint64
+			for range b.N {
+				b.StopTimer()
+				DefaultTables.TT.Reset()
+				s := NewSearch(*pos, DefaultTables)
+				b.StartTimer()
+				s.Search(context.TODO(), SearchParameter{Depth: 7, Infinite: true, Threads: threads})
+				nodes += s.totalNodes()
+			}
+			b.ReportMetric(float64(nodes)/b.Elapsed().Seconds(), "nps")
+		})
+	}
 }
 
 func BenchmarkSearchStartPos(b *testing.B) {
@@ -30,14 +50,24 @@ func BenchmarkSearchStartPos(b *testing.B) {
 	defer func() { os.Stdout = stdout }()
 	os.Stdout = os.NewFile(0, os.DevNull)
 
-	s := NewSearch(*position.New())
-	s.Search(context.TODO(), SearchParameter{Depth: 7, Infinite: true})
+	var nodes uint64
+	for range b.N {
+		b.StopTimer()
+		DefaultTables.TT.Reset()
+		DefaultTables.EvalCache.Clear()
+		s := NewSearch(*position.New(), DefaultTables)
+		b.StartTimer()
+		s.Search(context.TODO(), SearchParameter{Depth: 7, Infinite: true})
+		nodes += s.totalNodes()
+	}
+	b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+	b.ReportMetric(float64(nodes)/b.Elapsed().Seconds(), "nps")
 }
 
 func TestSearchTimeout(t *testing.T) {
 	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
 	require.NoError(t, err)
-	s := NewSearch(*pos)
+	s := NewSearch(*pos, DefaultTables)
 	s.Search(context.TODO(), SearchParameter{Depth: 10, MoveTime: 1000})
 }
 
@@ -46,6 +76,7 @@ func TestSearch(t *testing.T) {
 		name        string
 		fen         string
 		depth       uint8
+		threads     int
 		notExpected string
 		expected    string
 	}{
@@ -61,14 +92,22 @@ func TestSearch(t *testing.T) {
 			depth:       2,
 			notExpected: "a1a1",
 		},
+		{
+			name:        "position4 with threads",
+			fen:         "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+			depth:       5,
+			threads:     4,
+			notExpected: "a1a1",
+		},
 	}
 	for _, tt := range tests {
 		name := fmt.Sprintf("%v-%v", tt.name, tt.depth)
 		t.Run(name, func(t *testing.T) {
 			pos, err := position.NewFromFen(tt.fen)
 			assert.NoError(t, err)
-			s := NewSearch(*pos)
-			s.Search(context.TODO(), SearchParameter{Depth: tt.depth, Infinite: true})
+			s := NewSearch(*pos, DefaultTables)
+			s.Search(context.TODO(), SearchParameter{Depth: tt.depth, Infinite: true, Threads: tt.threads})
+			assert.GreaterOrEqual(t, s.Lines[0].SelDepth, tt.depth)
 			if tt.notExpected != "" {
 				assert.NotEqual(t, tt.notExpected, s.bestMove().String())
 			}
@@ -79,37 +118,381 @@ func TestSearch(t *testing.T) {
 	}
 }
 
-func Test_calculateTime(t *testing.T) {
-	type args struct {
-		sideToMove types.Color
-		plys       int
-		sp         SearchParameter
+func TestSearchMultiPV(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		multiPV int
+		lines   int
+	}{
+		{
+			name:    "startpos",
+			fen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			multiPV: 3,
+			lines:   3,
+		},
+		{
+			name:    "more lines than legal moves",
+			fen:     "7k/8/8/8/8/8/8/K7 w - - 0 1",
+			multiPV: 5,
+			lines:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			s := NewSearch(*pos, DefaultTables)
+			s.Search(context.TODO(), SearchParameter{Depth: 4, Infinite: true, MultiPV: tt.multiPV})
+			require.Len(t, s.Lines, tt.lines)
+			assert.Equal(t, s.bestMove(), s.Lines[0].PV.GetBestMove())
+			bestMoves := map[string]bool{}
+			for k, line := range s.Lines {
+				assert.Equal(t, k+1, line.MultiPV)
+				assert.Equal(t, uint8(4), line.Depth)
+				bestMoves[line.PV.GetBestMove().String()] = true
+			}
+			assert.Len(t, bestMoves, tt.lines)
+		})
+	}
+}
+
+func TestSearchSearchMoves(t *testing.T) {
+	pos := position.New()
+	searchMoves := []move.Move{}
+	for _, m := range []string{"a2a3", "h2h3"} {
+		sm, err := pos.MoveFromString(m)
+		require.NoError(t, err)
+		searchMoves = append(searchMoves, sm)
+	}
+
+	s := NewSearch(*pos, DefaultTables)
+	bestMove := s.Search(context.TODO(), SearchParameter{Depth: 4, Infinite: true, SearchMoves: searchMoves, MultiPV: 3})
+	assert.True(t, containsMove(searchMoves, bestMove))
+	assert.Len(t, s.Lines, 2)
+}
+
+func TestSearchIllegalSearchMoves(t *testing.T) {
+	pos := position.New()
+	var legal, illegal []move.Move
+	for _, m := range []string{"a2a3", "e2e5", "g1g3"} {
+		sm, err := pos.MoveFromString(m)
+		require.NoError(t, err)
+		if m == "a2a3" {
+			legal = append(legal, sm)
+		}
+		illegal = append(illegal, sm)
+	}
+
+	// The illegal moves are reported, but only the legal one is searched
+	s := NewSearch(*pos, DefaultTables)
+	r := &recordingReporter{}
+	s.Reporter = r
+	bestMove := s.Search(context.TODO(), SearchParameter{Depth: 3, Infinite: true, SearchMoves: illegal})
+	assert.Equal(t, legal[0].WithoutScore(), bestMove.WithoutScore())
+	assert.Equal(t, []string{"search move e2e5 is not legal", "search move g1g3 is not legal"}, r.messages)
+
+	// Without any legal search move, the search is not widened to the other moves
+	s = NewSearch(*pos, DefaultTables)
+	r = &recordingReporter{}
+	s.Reporter = r
+	bestMove = s.Search(context.TODO(), SearchParameter{Depth: 3, Infinite: true, SearchMoves: illegal[1:], Threads: 2})
+	assert.Equal(t, move.NullMove, bestMove)
+	assert.Equal(t, []string{"search move e2e5 is not legal", "search move g1g3 is not legal", "no legal search move"}, r.messages)
+	assert.Empty(t, r.iterations)
+	assert.Equal(t, []BestMoveEvent{{}}, r.bestMoves)
+}
+
+func TestSearchNodeLimit(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	var limit uint64 = 20000
+
+	// Node limited searches are reproducible
+	var bestMoves [2]move.Move
+	var nodes [2]uint64
+	for i := range bestMoves {
+		DefaultTables.TT.Reset()
+		s := NewSearch(*pos, DefaultTables)
+		bestMoves[i] = s.Search(context.TODO(), SearchParameter{Nodes: limit})
+		nodes[i] = s.totalNodes()
+		assert.NotEqual(t, move.NullMove, bestMoves[i])
+		assert.LessOrEqual(t, nodes[i], limit)
+	}
+	assert.Equal(t, bestMoves[0], bestMoves[1])
+	assert.Equal(t, nodes[0], nodes[1])
+}
+
+func TestSearchNodesTime(t *testing.T) {
+	s := NewSearch(*position.New(), DefaultTables)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ponderHit := s.limitSearch(ctx, cancel, SearchParameter{
+		WTime:     60000,
+		BTime:     60000,
+		WInc:      2000,
+		BInc:      2000,
+		NodesTime: 100,
+	})
+	assert.Equal(t, uint64(8000*100), s.nodeLimit.Load())
+	assert.NoError(t, ctx.Err())
+	_, open := <-ponderHit
+	assert.False(t, open)
+}
+
+func TestSearchPonder(t *testing.T) {
+	s := NewSearch(*position.New(), DefaultTables)
+	bestMove := make(chan move.Move)
+	go func() {
+		bestMove <- s.Search(context.TODO(), SearchParameter{MoveTime: 100, Depth: 3, Ponder: true})
+	}()
+
+	// While pondering, the search does not return, even if the search is finished
+	select {
+	case <-bestMove:
+		t.Fatal("search returned before the ponderhit")
+	case <-time.After(300 * time.Millisecond):
 	}
+
+	s.PonderHit()
+	select {
+	case m := <-bestMove:
+		assert.NotEqual(t, move.NullMove, m)
+		assert.NotEqual(t, move.NullMove, s.PonderMove())
+	case <-time.After(time.Second):
+		t.Fatal("search did not return after the ponderhit")
+	}
+}
+
+func TestSearchMate(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
-		want int
+		name     string
+		fen      string
+		mate     int
+		threads  int
+		found    bool
+		bestMove string
 	}{
 		{
-			name: "error case found on lichess",
-			args: args{
-				sideToMove: types.WHITE,
-				plys:       0,
-				sp: SearchParameter{
-					WTime: 60000,
-					BTime: 60000,
-					WInc:  2000,
-					BInc:  2000,
-				},
-			},
-			want: 2700,
+			name:     "back rank mate in 1",
+			fen:      "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1",
+			mate:     1,
+			found:    true,
+			bestMove: "d1d8",
+		},
+		{
+			name:     "mate in 2",
+			fen:      "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1",
+			mate:     2,
+			found:    true,
+			bestMove: "a1a6",
+		},
+		{
+			name:     "mate in 2 with threads",
+			fen:      "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1",
+			mate:     2,
+			threads:  4,
+			found:    true,
+			bestMove: "a1a6",
+		},
+		{
+			// The helpers finish iterations, before the main thread stops them
+			name:    "no mate in 3 with threads",
+			fen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			mate:    3,
+			threads: 4,
+			found:   false,
+		},
+		{
+			name:    "no mate in 1 with threads",
+			fen:     "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1",
+			mate:    1,
+			threads: 4,
+			found:   false,
+		},
+		{
+			name:  "no mate in 1",
+			fen:   "kbK5/pp6/1P6/8/8/8/8/R7 w - - 0 1",
+			mate:  1,
+			found: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := calculateTime(tt.args.sideToMove, tt.args.plys, tt.args.sp); got != tt.want {
-				t.Errorf("calculateTime() = %v, want %v", got, tt.want)
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			s := NewSearch(*pos, DefaultTables)
+			bestMove := s.Search(context.TODO(), SearchParameter{Mate: tt.mate, Threads: tt.threads})
+			assert.Equal(t, tt.found, s.isMateFound(tt.mate))
+			if tt.found {
+				assert.Equal(t, tt.bestMove, bestMove.String())
+				assert.Equal(t, tt.mate, evaluation.MateIn(s.Lines[0].Score))
 			}
 		})
 	}
 }
+
+func TestSearchMated(t *testing.T) {
+	// The side to move is mated in 4, so the aspiration window is at the lower limit of the scores
+	pos, err := position.NewFromFen("1Q6/8/5k2/4N3/3K4/P7/8/8 b - - 0 58")
+	require.NoError(t, err)
+	r := &recordingReporter{}
+	s := NewSearch(*pos, NewTables(1, 1))
+	s.Reporter = r
+	bestMove := s.Search(context.TODO(), SearchParameter{Depth: 8, Infinite: true})
+	assert.NotEqual(t, move.NullMove, bestMove)
+	for _, e := range append(r.iterations, r.aspirationFails...) {
+		assert.Negative(t, e.Score, "depth %v", e.Depth)
+	}
+	assert.True(t, evaluation.IsCheckmateValue(s.Lines[0].Score))
+	assert.Negative(t, s.Lines[0].Score)
+}
+
+func TestSearchParallelWithOwnTables(t *testing.T) {
+	fens := []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	}
+	sp := SearchParameter{Depth: 5, Infinite: true}
+
+	// Searches with their own tables are reproducible, even if running in parallel
+	search := func(fen string) (move.Move, uint64) {
+		pos, err := position.NewFromFen(fen)
+		require.NoError(t, err)
+		s := NewSearch(*pos, NewTables(4, 1))
+		bestMove := s.Search(context.TODO(), sp)
+		return bestMove, s.totalNodes()
+	}
+	wantMoves := make([]move.Move, len(fens))
+	wantNodes := make([]uint64, len(fens))
+	for i, fen := range fens {
+		wantMoves[i], wantNodes[i] = search(fen)
+	}
+
+	gotMoves := make([]move.Move, len(fens))
+	gotNodes := make([]uint64, len(fens))
+	var wg sync.WaitGroup
+	for i, fen := range fens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gotMoves[i], gotNodes[i] = search(fen)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, wantMoves, gotMoves)
+	assert.Equal(t, wantNodes, gotNodes)
+}
+
+func TestSearchEvaluator(t *testing.T) {
+	// The black queen is hanging
+	pos, err := position.NewFromFen("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, NewTables(1, 1))
+	s.Evaluator = evaluation.Material{}
+	bestMove := s.Search(context.TODO(), SearchParameter{Depth: 3, Infinite: true, Threads: 2})
+	assert.Equal(t, "d2d5", bestMove.String())
+	// After winning the queen only the rook is left
+	assert.Equal(t, evaluation.PieceValue[types.ROOK], s.Lines[0].Score)
+}
+
+func TestSearch_quiescence(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		quietChecks bool
+		want        int16
+	}{
+		{
+			name: "checkmated",
+			fen:  "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			want: -evaluation.INF,
+		},
+		{
+			name:        "mate by a quiet check",
+			fen:         "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			quietChecks: true,
+			want:        evaluation.INF - 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			s := NewSearch(*pos, NewTables(1, 1))
+			s.ctx = context.TODO()
+			score, err := s.quiescence(pos, -evaluation.INF, evaluation.INF, 0, tt.quietChecks)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, score)
+
+			// The result is stored in the transposition table for the quiescence search only
+			e, found := s.tables.TT.Probe(pos.ZobristHash, 0)
+			if tt.quietChecks {
+				require.True(t, found)
+				assert.Equal(t, transpositiontable.QuiescenceDepth, e.Depth)
+				assert.Equal(t, "a1a8", e.BestMove.String())
+				_, use := e.Cutoff(-evaluation.INF, evaluation.INF, 1)
+				assert.False(t, use)
+			}
+		})
+	}
+
+	// Without quiet checks, the mate is not found
+	pos, err := position.NewFromFen("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	require.NoError(t, err)
+	s := NewSearch(*pos, NewTables(1, 1))
+	s.ctx = context.TODO()
+	score, err := s.quiescence(pos, -evaluation.INF, evaluation.INF, 0, false)
+	require.NoError(t, err)
+	assert.False(t, evaluation.IsCheckmateValue(score))
+}
+
+func TestSearch_negamaxExcludedMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		excluded string
+		want     int16
+	}{
+		{
+			name:     "only legal move",
+			fen:      "7k/8/8/8/8/8/6r1/K7 w - - 0 1",
+			excluded: "a1b1",
+			want:     -100,
+		},
+		{
+			name:     "other moves",
+			fen:      "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			excluded: "e2a6",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := position.NewFromFen(tt.fen)
+			require.NoError(t, err)
+			s := NewSearch(*pos, NewTables(1, 1))
+			s.ctx = context.TODO()
+			excluded := moveFromString(t, pos, tt.excluded).WithoutScore()
+
+			// The position is searched as by the singular extension search below the root
+			s.stack[1].excludedMove = excluded
+			score, err := s.negamax(pos, -100, -99, 4, 1, false)
+			require.NoError(t, err)
+			if tt.want != 0 {
+				assert.Equal(t, tt.want, score)
+			}
+
+			// The result without the excluded move is not stored in the transposition table
+			_, found := s.tables.TT.Probe(pos.ZobristHash, 1)
+			assert.False(t, found)
+
+			// but the result of the position is
+			s.stack[1].excludedMove = move.NullMove
+			_, err = s.negamax(pos, -evaluation.INF, evaluation.INF, 4, 1, false)
+			require.NoError(t, err)
+			e, found := s.tables.TT.Probe(pos.ZobristHash, 1)
+			require.True(t, found)
+			assert.NotEqual(t, move.NullMove, e.BestMove)
+		})
+	}
+}
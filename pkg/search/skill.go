@@ -0,0 +1,157 @@
+package search
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+)
+
+// Limit Strength, see https://www.chessprogramming.org/Playing_Strength
+// The strength is weakened in a controlled way by the skill level.
+// The depth and the number of nodes are capped, the evaluation gets some noise
+// and the best move is chosen from several root candidates with a probability depending on their score loss.
+// All random decisions depend on the seed, so the results are reproducible with the same seed.
+// The noisy scores of a weakened search are stored in a transposition table of its own,
+// so they never reach the shared table used by the searches with the full strength.
+
+const (
+	// MaxSkillLevel is the skill level with the full strength.
+	MaxSkillLevel = 20
+	// MinElo and MaxElo are the limits of the UCI option `UCI_Elo`.
+	MinElo = 1000
+	MaxElo = 2400
+	// skillCandidates is the minimal number of lines searched to choose a weaker move from.
+	skillCandidates = 4
+	// skillNoise is the amplitude of the evaluation noise in centipawns per missing skill level.
+	skillNoise = 8
+	// skillTemperature is the score loss in centipawns per missing skill level, that reduces the probability of a candidate by the factor e.
+	skillTemperature = 10
+	// skillHashInMB is the size of the transposition table of the weakened searches,
+	// which is large enough for the node limit of the highest skill level.
+	skillHashInMB = 16
+)
+
+// EloToSkillLevel converts an Elo rating into a skill level.
+// The mapping interpolates linearly between MinElo and MaxElo,
+// so the rating is only an approximate strength scale and not a calibrated Elo rating.
+// The real strength of a rating is measured against Stockfish with the same `UCI_Elo`, e.g.
+//
+//	ELO=1500 CLEMENS_OPTIONS="option.UCI_LimitStrength=true option.UCI_Elo=1500" scripts/elo.sh
+//
+// and scripts/skill_elo.sh measures all skill levels this way,
+// so the interpolation can be replaced by the measured ratings.
+func EloToSkillLevel(elo int) int {
+	elo = min(max(elo, MinElo), MaxElo)
+	return (elo - MinElo) * MaxSkillLevel / (MaxElo - MinElo)
+}
+
+// skill weakens the search.
+type skill struct {
+	level int
+	seed  uint64
+	rng   *rand.Rand
+}
+
+// newSkill returns the skill for the search parameters or nil, if the search should use the full strength.
+// The random number generator is shared by all searches of a game, so every move gets a new draw.
+func newSkill(sp SearchParameter, rng *rand.Rand) *skill {
+	if !sp.LimitStrength || sp.SkillLevel >= MaxSkillLevel {
+		return nil
+	}
+	return &skill{
+		level: max(sp.SkillLevel, 0),
+		seed:  sp.SkillSeed,
+		rng:   rng,
+	}
+}
+
+// initSkill prepares the search for the skill of the search parameters.
+// The search is kept during a game, so the random number generator is only seeded once per game or if the seed changes.
+// A weakened search uses its own transposition table, which is cleared, if the noise changes with the skill.
+func (s *Search) initSkill(sp SearchParameter) {
+	previous := s.skill
+	s.tables = s.sharedTables
+	if s.skillRNG == nil || s.skillSeed != sp.SkillSeed {
+		s.skillRNG = rand.New(rand.NewPCG(sp.SkillSeed, 0))
+		s.skillSeed = sp.SkillSeed
+	}
+	s.skill = newSkill(sp, s.skillRNG)
+	if s.skill == nil {
+		return
+	}
+	if s.skillTT == nil {
+		s.skillTT = transpositiontable.New(skillHashInMB)
+	} else if previous == nil || previous.level != s.skill.level || previous.seed != s.skill.seed {
+		s.skillTT.Reset()
+	}
+	s.tables.TT = s.skillTT
+}
+
+// weakness is the number of missing skill levels to the full strength.
+func (sk *skill) weakness() int {
+	return MaxSkillLevel - sk.level
+}
+
+// maxDepth is the maximal depth searched on the skill level.
+func (sk *skill) maxDepth() uint8 {
+	return uint8(1 + sk.level/2)
+}
+
+// maxNodes is the maximal number of nodes searched on the skill level.
+func (sk *skill) maxNodes() uint64 {
+	return 1000 * uint64(sk.level+1) * uint64(sk.level+1)
+}
+
+// noise returns the evaluation noise for the position.
+// The noise only depends on the position and the seed, so the same position is always evaluated the same.
+func (sk *skill) noise(pos *position.Position) int16 {
+	// splitmix64 finalizer, see https://prng.di.unimi.it/splitmix64.c
+	z := pos.ZobristHash ^ sk.seed
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z = z ^ (z >> 31)
+	amplitude := uint64(skillNoise * sk.weakness())
+	return int16(z%(2*amplitude+1)) - int16(amplitude)
+}
+
+// chooseLine plays a line of a weakened search and reports it as the only line.
+// The line is chosen from the last depth completed for all lines, so the scores of the lines are comparable.
+func (s *Search) chooseLine() {
+	lines := s.completedLines
+	if len(lines) == 0 {
+		lines = s.Lines[:1]
+	}
+	line := lines[s.skill.pickLine(lines)]
+	if line.PV.GetBestMove() == move.NullMove {
+		return
+	}
+	s.PV = line.PV
+	line.MultiPV = 1
+	s.Reporter.Iteration(s.iterationEvent(line, Exact))
+}
+
+// pickLine chooses one of the lines with a probability depending on the score loss to the best line.
+func (sk *skill) pickLine(lines []Info) int {
+	temperature := float64(skillTemperature * sk.weakness())
+	weights := make([]float64, len(lines))
+	sum := 0.0
+	for i, l := range lines {
+		if l.PV.GetBestMove() == move.NullMove {
+			continue
+		}
+		loss := float64(lines[0].Score) - float64(l.Score)
+		weights[i] = math.Exp(-max(loss, 0) / temperature)
+		sum += weights[i]
+	}
+	r := sk.rng.Float64() * sum
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return 0
+}
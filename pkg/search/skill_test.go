@@ -0,0 +1,201 @@
+package search
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEloToSkillLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		elo  int
+		want int
+	}{
+		{name: "below minimum", elo: 500, want: 0},
+		{name: "minimum", elo: MinElo, want: 0},
+		{name: "in between", elo: 1700, want: 10},
+		{name: "maximum", elo: MaxElo, want: MaxSkillLevel},
+		{name: "above maximum", elo: 3000, want: MaxSkillLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EloToSkillLevel(tt.elo))
+		})
+	}
+}
+
+func Test_newSkill(t *testing.T) {
+	assert.Nil(t, newSkill(SearchParameter{}, nil))
+	assert.Nil(t, newSkill(SearchParameter{LimitStrength: true, SkillLevel: MaxSkillLevel}, nil))
+	sk := newSkill(SearchParameter{LimitStrength: true, SkillLevel: 4}, nil)
+	require.NotNil(t, sk)
+	assert.Equal(t, uint8(3), sk.maxDepth())
+	assert.Equal(t, uint64(25000), sk.maxNodes())
+}
+
+func Test_skill_noise(t *testing.T) {
+	pos := position.New()
+	sk := newSkill(SearchParameter{LimitStrength: true, SkillLevel: 0, SkillSeed: 42}, nil)
+	noise := sk.noise(pos)
+	assert.Equal(t, noise, sk.noise(pos))
+	assert.LessOrEqual(t, noise, int16(skillNoise*MaxSkillLevel))
+	assert.GreaterOrEqual(t, noise, -int16(skillNoise*MaxSkillLevel))
+
+	other := newSkill(SearchParameter{LimitStrength: true, SkillLevel: 0, SkillSeed: 43}, nil)
+	assert.NotEqual(t, noise, other.noise(pos))
+}
+
+func Test_skill_pickLine(t *testing.T) {
+	var m1, m2 move.Move
+	m1.SetSourceSquare(types.SQUARE_E2)
+	m1.SetTargetSquare(types.SQUARE_E4)
+	m2.SetSourceSquare(types.SQUARE_D2)
+	m2.SetTargetSquare(types.SQUARE_D4)
+	lines := make([]Info, 3)
+	lines[0].PV = pvline.New(m1)
+	lines[0].Score = 50
+	lines[1].PV = pvline.New(m2)
+	lines[1].Score = -1000
+	// The last line is empty and never chosen
+
+	tests := []struct {
+		name      string
+		level     int
+		wantWorse bool
+	}{
+		{name: "strong player never blunders", level: MaxSkillLevel - 1, wantWorse: false},
+		{name: "weak player sometimes blunders", level: 0, wantWorse: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sk := newSkill(SearchParameter{LimitStrength: true, SkillLevel: tt.level, SkillSeed: 1}, rand.New(rand.NewPCG(1, 0)))
+			var picked [3]int
+			for range 1000 {
+				picked[sk.pickLine(lines)]++
+			}
+			assert.Greater(t, picked[0], picked[1])
+			assert.Equal(t, tt.wantWorse, picked[1] > 0)
+			assert.Equal(t, 0, picked[2])
+		})
+	}
+}
+
+func TestSearch_initSkill(t *testing.T) {
+	tables := NewTables(1, 1)
+	s := NewSearch(*position.New(), tables)
+	weak := SearchParameter{LimitStrength: true, SkillLevel: 2, SkillSeed: 7}
+
+	// The full strength uses the shared tables
+	s.initSkill(SearchParameter{})
+	assert.Nil(t, s.skill)
+	assert.Same(t, tables.TT, s.tables.TT)
+
+	// A weakened search uses its own transposition table, but the shared evaluation cache
+	s.initSkill(weak)
+	require.NotNil(t, s.skill)
+	assert.NotSame(t, tables.TT, s.tables.TT)
+	assert.Same(t, s.skillTT, s.tables.TT)
+	assert.Same(t, tables.EvalCache, s.tables.EvalCache)
+
+	// The random number generator is seeded once and not for every search
+	rng := s.skillRNG
+	s.initSkill(weak)
+	assert.Same(t, rng, s.skill.rng)
+	s.initSkill(SearchParameter{LimitStrength: true, SkillLevel: 2, SkillSeed: 8})
+	assert.NotSame(t, rng, s.skill.rng)
+
+	// The entries of another skill are removed
+	s.skillTT.PotentiallySave(s.Pos.ZobristHash, move.NullMove, 1, 0, 0, 0, transpositiontable.AlphaNode)
+	s.initSkill(SearchParameter{LimitStrength: true, SkillLevel: 3, SkillSeed: 8})
+	_, found := s.skillTT.Probe(s.Pos.ZobristHash, 0)
+	assert.False(t, found)
+
+	s.initSkill(SearchParameter{})
+	assert.Nil(t, s.skill)
+	assert.Same(t, tables.TT, s.tables.TT)
+}
+
+func TestSearchLimitStrength(t *testing.T) {
+	pos, err := position.NewFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	sp := SearchParameter{LimitStrength: true, SkillLevel: 2, SkillSeed: 7, Threads: 4}
+
+	// Weakened searches are reproducible with the same seed
+	var bestMoves [2]move.Move
+	for i := range bestMoves {
+		DefaultTables.TT.Reset()
+		s := NewSearch(*pos, DefaultTables)
+		r := &recordingReporter{}
+		s.Reporter = r
+		bestMoves[i] = s.Search(context.TODO(), sp)
+		// Only the chosen line is reported
+		require.Len(t, r.iterations, 1)
+		assert.Equal(t, 1, r.iterations[0].MultiPV)
+		assert.Equal(t, bestMoves[i], r.iterations[0].PV.GetBestMove())
+		assert.Empty(t, r.aspirationFails)
+		assert.NotEqual(t, move.NullMove, bestMoves[i])
+		assert.LessOrEqual(t, s.totalNodes(), s.skill.maxNodes())
+		assert.LessOrEqual(t, s.Lines[0].Depth, s.skill.maxDepth())
+		assert.Len(t, s.Lines, skillCandidates)
+		assert.Empty(t, s.helpers)
+		// The noisy scores do not reach the shared transposition table
+		_, found := DefaultTables.TT.Probe(pos.ZobristHash, 0)
+		assert.False(t, found)
+		// and the noisy static evaluations are not stored at all
+		e, found := s.skillTT.Probe(pos.ZobristHash, 0)
+		assert.True(t, found)
+		assert.Equal(t, transpositiontable.NoStaticEval, e.StaticEval)
+	}
+	assert.Equal(t, bestMoves[0], bestMoves[1])
+}
+
+func TestSearch_chooseLine(t *testing.T) {
+	var m1, m2, m3 move.Move
+	m1.SetSourceSquare(types.SQUARE_E2)
+	m1.SetTargetSquare(types.SQUARE_E4)
+	m2.SetSourceSquare(types.SQUARE_D2)
+	m2.SetTargetSquare(types.SQUARE_D4)
+	m3.SetSourceSquare(types.SQUARE_G1)
+	m3.SetTargetSquare(types.SQUARE_F3)
+
+	s := NewSearch(*position.New(), NewTables(1, 1))
+	r := &recordingReporter{}
+	s.Reporter = r
+	s.initSkill(SearchParameter{LimitStrength: true, SkillLevel: 0, SkillSeed: 1})
+
+	// Without a completed depth, the principal variation is played
+	s.Lines = []Info{{PV: pvline.New(m1), Depth: 1, MultiPV: 1}, {}}
+	s.chooseLine()
+	assert.Equal(t, m1, s.bestMove())
+	require.Len(t, r.iterations, 1)
+	assert.Equal(t, 1, r.iterations[0].MultiPV)
+
+	// The second line of an unfinished depth is never chosen,
+	// even though its score is not comparable with the scores of the completed depth
+	s.completedLines = []Info{
+		{PV: pvline.New(m1), Score: 0, Depth: 3, MultiPV: 1},
+		{PV: pvline.New(m2), Score: -10, Depth: 3, MultiPV: 2},
+	}
+	s.Lines = []Info{
+		{PV: pvline.New(m1), Score: 0, Depth: 4, MultiPV: 1},
+		{PV: pvline.New(m3), Score: 100, Depth: 4, MultiPV: 2},
+	}
+	chosen := map[move.Move]int{}
+	for range 100 {
+		s.chooseLine()
+		chosen[s.bestMove()]++
+	}
+	assert.Positive(t, chosen[m1])
+	assert.Positive(t, chosen[m2])
+	assert.Zero(t, chosen[m3])
+	assert.Equal(t, uint8(3), r.iterations[len(r.iterations)-1].Depth)
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"math"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// stackEntry contains the data of a single ply of the current search path.
+type stackEntry struct {
+	// staticEval is the static evaluation of the position, which is not set, if in check.
+	staticEval int16
+	// currentMove is the move searched from this ply, which is move.NullMove for a null move.
+	currentMove move.Move
+	// movedPiece is the piece moved by the current move.
+	movedPiece types.Piece
+	// excludedMove is not searched in this ply.
+	excludedMove move.Move
+	inCheck      bool
+}
+
+// searchStack is indexed by the ply, which is always smaller than the size of the stack.
+type searchStack [math.MaxUint8 + 1]stackEntry
+
+// improving returns true, if the static evaluation of the side to move is better than two plies ago.
+// If the side to move was in check two plies ago, the evaluation of four plies ago is used.
+// Without any evaluation to compare, the position is assumed to improve.
+// https://www.chessprogramming.org/Improving
+func (st *searchStack) improving(ply uint8) bool {
+	e := &st[ply]
+	if e.inCheck {
+		return false
+	}
+	if ply >= 2 && !st[ply-2].inCheck {
+		return e.staticEval > st[ply-2].staticEval
+	}
+	if ply >= 4 && !st[ply-4].inCheck {
+		return e.staticEval > st[ply-4].staticEval
+	}
+	return true
+}
+
+// previousMove returns the move, which lead to the position at this ply.
+func (st *searchStack) previousMove(ply uint8) move.Move {
+	if ply == 0 {
+		return move.NullMove
+	}
+	return st[ply-1].currentMove
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_searchStack_improving(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []stackEntry
+		want    bool
+	}{
+		{
+			name:    "root",
+			entries: []stackEntry{{staticEval: -100}},
+			want:    true,
+		},
+		{
+			name:    "better than two plies ago",
+			entries: []stackEntry{{staticEval: 10}, {staticEval: -500}, {staticEval: 20}},
+			want:    true,
+		},
+		{
+			name:    "worse than two plies ago",
+			entries: []stackEntry{{staticEval: 30}, {staticEval: -500}, {staticEval: 20}},
+			want:    false,
+		},
+		{
+			name:    "in check",
+			entries: []stackEntry{{staticEval: 10}, {}, {inCheck: true}},
+			want:    false,
+		},
+		{
+			name:    "in check two plies ago",
+			entries: []stackEntry{{staticEval: 30}, {}, {inCheck: true}, {}, {staticEval: 20}},
+			want:    false,
+		},
+		{
+			name:    "in check two and four plies ago",
+			entries: []stackEntry{{inCheck: true}, {}, {inCheck: true}, {}, {staticEval: -200}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := searchStack{}
+			copy(st[:], tt.entries)
+			assert.Equal(t, tt.want, st.improving(uint8(len(tt.entries)-1)))
+		})
+	}
+}
+
+func Test_searchStack_previousMove(t *testing.T) {
+	m := *new(move.Move).SetSourceSquare(types.SQUARE_E2).SetTargetSquare(types.SQUARE_E4)
+	st := searchStack{}
+	st[0].currentMove = m
+	assert.Equal(t, move.NullMove, st.previousMove(0))
+	assert.Equal(t, m, st.previousMove(1))
+}
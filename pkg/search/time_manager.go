@@ -0,0 +1,185 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/types"
+)
+
+// Time Management, see https://www.chessprogramming.org/Time_Management
+// The search is limited by two limits.
+// The hard limit stops the search immediately, even in the middle of an iteration.
+// The soft limit is only checked after an iteration. It is scaled by the stability of the best move and the score,
+// so the search stops early in obvious positions and takes more time, if the search is unsure.
+
+const (
+	// DefaultMoveOverhead is the default time in milliseconds lost per move, e.g. for the communication with the GUI.
+	DefaultMoveOverhead = 10
+	// defaultMovesToGo is the number of moves expected until the end of the game, if the GUI does not tell us.
+	defaultMovesToGo = 50
+	// minMovesToGo is the minimal number of moves expected until the end of the game.
+	minMovesToGo = 20
+	// hardLimitFactor is the maximal factor the hard limit exceeds the soft limit.
+	hardLimitFactor = 3
+	// maxHardLimitShare is the maximal share of the remaining time in percent a single move may use without `movestogo`.
+	maxHardLimitShare = 10
+	// maxHardLimitShareBeforeTimeControl is the maximal share of the remaining time in percent a single move may use with `movestogo`.
+	// The last move before the time control gets all of the remaining time,
+	// but the rest is kept as reserve for the delays of the GUI and the network, which are not covered by the move overhead.
+	maxHardLimitShareBeforeTimeControl = 80
+	// scoreDropMargin is the drop of the score in centipawns, that leads to an extension of the time.
+	scoreDropMargin = 30
+)
+
+// stabilityScale is the scale of the soft limit in percent by the number of iterations the best move did not change.
+var stabilityScale = [...]int{150, 120, 100, 85, 70}
+
+type timeManager struct {
+	mu sync.Mutex
+	// started is false while pondering, since the time only runs after the ponderhit.
+	started    bool
+	start      time.Time
+	startNodes uint64
+	soft       int
+	hard       int
+	// fixed is true for the movetime mode, in which the time is simply used up.
+	fixed bool
+	// nodesTime measures the time in nodes instead of milliseconds, see the UCI option `nodestime`.
+	nodesTime int
+
+	// State of the iterations
+	lastBestMove       move.Move
+	lastScore          int16
+	lastIteration      int
+	lastIterationStart int
+	stability          int
+}
+
+// calculateTimeLimits calculates the soft and the hard time limit in milliseconds for the side to move.
+func calculateTimeLimits(sideToMove types.Color, plys int, sp SearchParameter) (soft, hard int) {
+	if sp.MoveTime > 0 {
+		movetime := max(sp.MoveTime-sp.MoveOverhead, 1)
+		return movetime, movetime
+	}
+
+	var t, inc int
+	if sideToMove == types.BLACK {
+		t = sp.BTime
+		inc = sp.BInc
+	} else {
+		t = sp.WTime
+		inc = sp.WInc
+	}
+	// I do not know, calculate a second
+	if t <= 0 {
+		movetime := max(1000-sp.MoveOverhead, 1)
+		return movetime, movetime
+	}
+
+	movesToGo := sp.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = max(defaultMovesToGo-plys/2, minMovesToGo)
+	}
+
+	// The time left for the remaining moves, including the increments and the overhead for every move
+	timeLeft := max(t+inc*(movesToGo-1)-sp.MoveOverhead*(movesToGo+1), 1)
+	soft = timeLeft / movesToGo
+
+	// Never use more than a share of the remaining time for a single move,
+	// so there is still time left for the next moves.
+	hard = min(soft*hardLimitFactor, t*maxHardLimitShareBeforeTimeControl/100-sp.MoveOverhead, maxTimeInMs)
+	if sp.MovesToGo <= 0 {
+		hard = min(hard, t*maxHardLimitShare/100+inc-sp.MoveOverhead)
+	}
+	hard = max(hard, 1)
+	soft = max(min(soft, hard), 1)
+	return soft, hard
+}
+
+// newTimeManager creates a time manager with the given limits, which is not yet started.
+func newTimeManager(soft, hard int, fixed bool, nodesTime int) *timeManager {
+	return &timeManager{
+		soft:      soft,
+		hard:      hard,
+		fixed:     fixed,
+		nodesTime: nodesTime,
+	}
+}
+
+// startTime starts the clock and enforces the hard limit.
+// In the nodestime mode the hard limit is converted into a node limit to be independent of the hardware.
+// It is called by the goroutine waiting for the ponderhit, so the limits are queued for the main thread to report.
+func (s *Search) startTime(cancel context.CancelFunc) {
+	tm := s.tm
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.started = true
+	tm.start = time.Now()
+	tm.startNodes = s.totalNodes()
+
+	if tm.nodesTime > 0 {
+		nodeLimit := tm.startNodes + uint64(tm.hard)*uint64(tm.nodesTime)
+		if limit := s.nodeLimit.Load(); limit > 0 && limit < nodeLimit {
+			nodeLimit = limit
+		}
+		s.nodeLimit.Store(nodeLimit)
+		s.messages.push(fmt.Sprintf("calculated node limit %v", nodeLimit))
+		return
+	}
+	s.messages.push(fmt.Sprintf("calculated time limits soft %v hard %v", tm.soft, tm.hard))
+	time.AfterFunc(time.Duration(tm.hard)*time.Millisecond, cancel)
+}
+
+// elapsed returns the elapsed time in milliseconds since the start.
+func (tm *timeManager) elapsed(nodes uint64) int {
+	if tm.nodesTime > 0 {
+		return int((nodes - tm.startNodes) / uint64(tm.nodesTime))
+	}
+	return int(time.Since(tm.start).Milliseconds())
+}
+
+// shouldStop is called after every completed iteration and returns true,
+// if the search should not start the next iteration.
+func (tm *timeManager) shouldStop(bestMove move.Move, score int16, nodes uint64) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	// Update the stability of the best move, which is also done while pondering.
+	if bestMove.WithoutScore() == tm.lastBestMove.WithoutScore() {
+		tm.stability = min(tm.stability+1, len(stabilityScale)-1)
+	} else {
+		tm.stability = 0
+	}
+	scoreDropped := tm.lastBestMove != move.NullMove && score < tm.lastScore-scoreDropMargin
+	tm.lastBestMove = bestMove
+	tm.lastScore = score
+
+	if !tm.started {
+		return false
+	}
+
+	elapsed := tm.elapsed(nodes)
+	tm.lastIteration = elapsed - tm.lastIterationStart
+	tm.lastIterationStart = elapsed
+	if tm.fixed {
+		return false
+	}
+
+	// Take less time, if the best move is stable, and more time, if the best move changes or the score drops.
+	soft := tm.soft * stabilityScale[tm.stability] / 100
+	if scoreDropped {
+		soft = soft * 3 / 2
+	}
+	soft = min(soft, tm.hard)
+	if elapsed >= soft {
+		return true
+	}
+
+	// Do not start a new iteration, which is unlikely to finish before the hard limit.
+	// The next iteration is estimated to take at least twice as long as the last one.
+	return elapsed+2*tm.lastIteration >= tm.hard
+}
@@ -0,0 +1,228 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_calculateTimeLimits(t *testing.T) {
+	type args struct {
+		sideToMove types.Color
+		plys       int
+		sp         SearchParameter
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantSoft int
+		wantHard int
+	}{
+		{
+			name: "error case found on lichess",
+			args: args{
+				sideToMove: types.WHITE,
+				plys:       0,
+				sp: SearchParameter{
+					WTime: 60000,
+					BTime: 60000,
+					WInc:  2000,
+					BInc:  2000,
+				},
+			},
+			wantSoft: 3160,
+			wantHard: 8000,
+		},
+		{
+			name: "black with move overhead",
+			args: args{
+				sideToMove: types.BLACK,
+				plys:       0,
+				sp: SearchParameter{
+					WTime:        1000,
+					BTime:        60000,
+					MoveOverhead: 100,
+				},
+			},
+			wantSoft: 1098,
+			wantHard: 3294,
+		},
+		{
+			name: "movestogo",
+			args: args{
+				sideToMove: types.WHITE,
+				plys:       60,
+				sp: SearchParameter{
+					WTime:     10000,
+					MovesToGo: 5,
+				},
+			},
+			wantSoft: 2000,
+			wantHard: 6000,
+		},
+		{
+			name: "last move before time control",
+			args: args{
+				sideToMove: types.WHITE,
+				plys:       78,
+				sp: SearchParameter{
+					WTime:        10000,
+					MovesToGo:    1,
+					MoveOverhead: 50,
+				},
+			},
+			wantSoft: 7950,
+			wantHard: 7950,
+		},
+		{
+			name: "movetime",
+			args: args{
+				sideToMove: types.WHITE,
+				sp: SearchParameter{
+					WTime:        10000,
+					MoveTime:     500,
+					MoveOverhead: 50,
+				},
+			},
+			wantSoft: 450,
+			wantHard: 450,
+		},
+		{
+			name: "almost no time left",
+			args: args{
+				sideToMove: types.WHITE,
+				sp: SearchParameter{
+					WTime:        10,
+					MoveOverhead: 50,
+				},
+			},
+			wantSoft: 1,
+			wantHard: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			soft, hard := calculateTimeLimits(tt.args.sideToMove, tt.args.plys, tt.args.sp)
+			assert.Equal(t, tt.wantSoft, soft)
+			assert.Equal(t, tt.wantHard, hard)
+		})
+	}
+}
+
+func Test_calculateTimeLimits_lichess(t *testing.T) {
+	// The error case found on lichess with a 1+2 clock, which used a single time limit of 2.7 seconds.
+	sp := SearchParameter{WTime: 60000, BTime: 60000, WInc: 2000, BInc: 2000}
+	soft, hard := calculateTimeLimits(types.WHITE, 0, sp)
+	assert.InDelta(t, 2700, soft, 500)
+	assert.LessOrEqual(t, hard, hardLimitFactor*2700)
+	assert.LessOrEqual(t, hard, sp.WTime*maxHardLimitShare/100+sp.WInc)
+
+	// Even if all moves are stopped by the hard limit, a third of the time is left after ten moves
+	// and the time never runs out.
+	for ply := 0; ply < 200; ply += 2 {
+		_, hard := calculateTimeLimits(types.WHITE, ply, sp)
+		sp.WTime += sp.WInc - hard
+		assert.Positive(t, sp.WTime, "ply %v", ply)
+		if ply == 18 {
+			assert.GreaterOrEqual(t, sp.WTime, 60000/3)
+		}
+	}
+}
+
+func Test_timeManager_shouldStop(t *testing.T) {
+	var m1, m2 move.Move
+	m1.SetSourceSquare(types.SQUARE_E2)
+	m1.SetTargetSquare(types.SQUARE_E4)
+	m2.SetSourceSquare(types.SQUARE_D2)
+	m2.SetTargetSquare(types.SQUARE_D4)
+
+	type iteration struct {
+		bestMove move.Move
+		score    int16
+		nodes    uint64
+		want     bool
+	}
+	tests := []struct {
+		name       string
+		soft       int
+		hard       int
+		fixed      bool
+		notStarted bool
+		iterations []iteration
+	}{
+		{
+			name: "stable best move stops early",
+			soft: 100,
+			hard: 400,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m1, score: 20, nodes: 20, want: false},
+				{bestMove: m1, score: 20, nodes: 30, want: false},
+				{bestMove: m1, score: 20, nodes: 40, want: false},
+				{bestMove: m1, score: 20, nodes: 75, want: true},
+			},
+		},
+		{
+			name: "changing best move extends",
+			soft: 100,
+			hard: 400,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m2, score: 20, nodes: 20, want: false},
+				{bestMove: m1, score: 20, nodes: 120, want: false},
+			},
+		},
+		{
+			name: "score drop extends",
+			soft: 100,
+			hard: 400,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m1, score: 20, nodes: 20, want: false},
+				{bestMove: m1, score: -50, nodes: 130, want: false},
+				{bestMove: m1, score: -50, nodes: 140, want: true},
+			},
+		},
+		{
+			name: "next iteration does not finish",
+			soft: 300,
+			hard: 400,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m2, score: 20, nodes: 150, want: true},
+			},
+		},
+		{
+			name:  "movetime is used up",
+			soft:  100,
+			hard:  100,
+			fixed: true,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m1, score: 20, nodes: 90, want: false},
+			},
+		},
+		{
+			name:       "not started while pondering",
+			soft:       100,
+			hard:       400,
+			notStarted: true,
+			iterations: []iteration{
+				{bestMove: m1, score: 20, nodes: 10, want: false},
+				{bestMove: m1, score: 20, nodes: 1000, want: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The time is measured in nodes with one node per millisecond to be reproducible
+			tm := newTimeManager(tt.soft, tt.hard, tt.fixed, 1)
+			tm.started = !tt.notStarted
+			for _, i := range tt.iterations {
+				assert.Equal(t, i.want, tm.shouldStop(i.bestMove, i.score, i.nodes))
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package transpositiontable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/shaardie/clemens/pkg/position"
+)
+
+// A stored table starts with a header followed by the entries, which are not empty.
+// All numbers are little endian.
+// The static evaluations are not loaded, since the stored table might come from another evaluator or engine version,
+// but the results of the search are.
+
+// fileMagic identifies a file of a stored table.
+var fileMagic = [8]byte{'c', 'l', 'e', 'm', 'e', 'n', 's', 't'}
+
+const (
+	// fileVersion is the version of the file format, which changes with the layout of the entries.
+	fileVersion uint32 = 1
+	// fileEntrySize is the size of an entry with its index, key and data.
+	fileEntrySize = 8 + 4 + 8
+)
+
+var (
+	ErrInvalidFile         = errors.New("not a transposition table file")
+	ErrUnsupportedVersion  = errors.New("unsupported transposition table file version")
+	ErrZobristKeysMismatch = errors.New("transposition table file uses different zobrist keys")
+	ErrTableSizeMismatch   = errors.New("transposition table file has a different table size")
+	ErrInvalidEntry        = errors.New("invalid entry in transposition table file")
+)
+
+// fileHeader is the header of a stored table.
+type fileHeader struct {
+	Magic   [8]byte
+	Version uint32
+	// Generation is the generation of the last search.
+	Generation uint32
+	// ZobristFingerprint identifies the zobrist keys, which were used to calculate the hashes of the entries.
+	ZobristFingerprint uint64
+	// NumberOfBuckets is the size of the table, which is needed to find the bucket of an entry.
+	NumberOfBuckets uint64
+	// Entries is the number of stored entries.
+	Entries uint64
+}
+
+// Save writes all entries of the table to w.
+// It must not be called while a search is running.
+func (tt *TranspositionTable) Save(w io.Writer) error {
+	return tt.save(w, position.ZobristFingerprint())
+}
+
+func (tt *TranspositionTable) save(w io.Writer, zobristFingerprint uint64) error {
+	var entries uint64
+	for i := range tt.buckets {
+		for j := range bucketSize {
+			if !tt.buckets[i].isEmpty(j) {
+				entries++
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	err := binary.Write(bw, binary.LittleEndian, fileHeader{
+		Magic:              fileMagic,
+		Version:            fileVersion,
+		Generation:         tt.generation.Load(),
+		ZobristFingerprint: zobristFingerprint,
+		NumberOfBuckets:    tt.numberOfBuckets,
+		Entries:            entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Every entry is stored with its index in the table and its raw key and data,
+	// since the zobrist hash of the entry is not known anymore.
+	buf := make([]byte, 0, fileEntrySize)
+	for i := range tt.buckets {
+		b := &tt.buckets[i]
+		for j := range bucketSize {
+			if b.isEmpty(j) {
+				continue
+			}
+			buf = binary.LittleEndian.AppendUint64(buf[:0], uint64(i)*bucketSize+uint64(j))
+			buf = binary.LittleEndian.AppendUint32(buf, b.keys[j].Load())
+			buf = binary.LittleEndian.AppendUint64(buf, b.data[j].Load())
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Load replaces the entries of the table with the ones read from r.
+// The file has to be written with the same zobrist keys and for a table of the same size.
+// The table is left untouched, if the header does not match, and is empty, if the entries are broken.
+// It must not be called while a search is running.
+func (tt *TranspositionTable) Load(r io.Reader) error {
+	return tt.load(r, position.ZobristFingerprint())
+}
+
+func (tt *TranspositionTable) load(r io.Reader, zobristFingerprint uint64) error {
+	br := bufio.NewReader(r)
+	var h fileHeader
+	if err := binary.Read(br, binary.LittleEndian, &h); err != nil {
+		return fmt.Errorf("%w, %w", ErrInvalidFile, err)
+	}
+	if h.Magic != fileMagic {
+		return ErrInvalidFile
+	}
+	if h.Version != fileVersion {
+		return fmt.Errorf("%w %v", ErrUnsupportedVersion, h.Version)
+	}
+	if h.ZobristFingerprint != zobristFingerprint {
+		return ErrZobristKeysMismatch
+	}
+	if h.NumberOfBuckets != tt.numberOfBuckets {
+		return fmt.Errorf("%w, the file needs a table of %v MB", ErrTableSizeMismatch, h.NumberOfBuckets*uint64(unsafe.Sizeof(bucket{}))/1024/1024)
+	}
+	if h.Generation == 0 || h.Generation > generationCycle {
+		return fmt.Errorf("%w, generation %v", ErrInvalidFile, h.Generation)
+	}
+
+	tt.Reset()
+	buf := make([]byte, fileEntrySize)
+	for range h.Entries {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			tt.Reset()
+			return fmt.Errorf("%w, %w", ErrInvalidEntry, err)
+		}
+		index := binary.LittleEndian.Uint64(buf)
+		key := binary.LittleEndian.Uint32(buf[8:])
+		data := ttData(binary.LittleEndian.Uint64(buf[12:]))
+		if index >= tt.numberOfBuckets*bucketSize || data == 0 {
+			tt.Reset()
+			return ErrInvalidEntry
+		}
+		// The key contains the upper half of the zobrist hash, which is needed for the key of the changed data.
+		upperHash := uint64(key^ttKey(0, data)) << 32
+		data = data.withoutStaticEval()
+		tt.buckets[index/bucketSize].store(int(index%bucketSize), upperHash, data)
+	}
+	tt.generation.Store(h.Generation)
+	return nil
+}
@@ -0,0 +1,91 @@
+package transpositiontable
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveLoad(t *testing.T) {
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(28)
+
+	tt := New(1)
+	tt.NewGeneration()
+	tt.PotentiallySave(1234, m, 5, 0, 42, 17, PVNode)
+	tt.PotentiallySave(5678, move.NullMove, 3, 0, -13, NoStaticEval, AlphaNode)
+	tt.PotentiallySave(0xdeadbeef<<32|91011, m, 7, 0, 99, -5, BetaNode)
+
+	var buf bytes.Buffer
+	require.NoError(t, tt.Save(&buf))
+	assert.Equal(t, int(40+3*fileEntrySize), buf.Len())
+	file := buf.Bytes()
+
+	loaded := New(1)
+	loaded.PotentiallySave(42, m, 1, 0, 0, 0, PVNode)
+	require.NoError(t, loaded.Load(bytes.NewReader(file)))
+	assert.Equal(t, tt.getGeneration(), loaded.getGeneration())
+	for _, hash := range []uint64{1234, 5678, 0xdeadbeef<<32 | 91011, 42} {
+		want, wantFound := tt.Probe(hash, 0)
+		got, found := loaded.Probe(hash, 0)
+		assert.Equal(t, wantFound, found)
+		// The static evaluations are not loaded
+		if wantFound {
+			want.StaticEval = NoStaticEval
+		}
+		assert.Equal(t, want, got)
+	}
+
+	tests := []struct {
+		name               string
+		file               []byte
+		sizeInMB           int
+		zobristFingerprint uint64
+		wantErr            error
+	}{
+		{
+			name:    "empty file",
+			file:    []byte{},
+			wantErr: ErrInvalidFile,
+		},
+		{
+			name:    "no table",
+			file:    bytes.Repeat([]byte{1}, len(file)),
+			wantErr: ErrInvalidFile,
+		},
+		{
+			name:               "other zobrist keys",
+			file:               file,
+			zobristFingerprint: position.ZobristFingerprint() + 1,
+			wantErr:            ErrZobristKeysMismatch,
+		},
+		{
+			name:     "other table size",
+			file:     file,
+			sizeInMB: 2,
+			wantErr:  ErrTableSizeMismatch,
+		},
+		{
+			name:    "truncated",
+			file:    file[:len(file)-1],
+			wantErr: ErrInvalidEntry,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := New(max(tt.sizeInMB, 1))
+			zobristFingerprint := tt.zobristFingerprint
+			if zobristFingerprint == 0 {
+				zobristFingerprint = position.ZobristFingerprint()
+			}
+			err := table.load(bytes.NewReader(tt.file), zobristFingerprint)
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, found := table.Probe(1234, 0)
+			assert.False(t, found)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package transpositiontable
+
+import "sync/atomic"
+
+// statsSampleShift selects the positions, which are counted in the statistics.
+// Only positions with the upper bits of the zobrist hash set to zero are counted,
+// so the threads do not compete for the counters on every probe.
+// The zobrist hashes are random, so the sample is representative for all positions.
+const statsSampleShift = 58
+
+// Stats are the statistics of the usage of the table.
+// Only a sample of the positions is counted.
+type Stats struct {
+	// Probes is the number of probes.
+	Probes uint64
+	// Hits is the number of probes, which found an entry for the position.
+	Hits uint64
+	// Stores is the number of new entries.
+	Stores uint64
+	// Collisions is the number of new entries, which replaced the entry of another position.
+	Collisions uint64
+}
+
+// HitRate returns the ratio of the probes, which found an entry.
+func (s Stats) HitRate() float64 {
+	if s.Probes == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Probes)
+}
+
+// CollisionRate returns the ratio of the new entries, which replaced the entry of another position.
+func (s Stats) CollisionRate() float64 {
+	if s.Stores == 0 {
+		return 0
+	}
+	return float64(s.Collisions) / float64(s.Stores)
+}
+
+type stats struct {
+	probes     atomic.Uint64
+	hits       atomic.Uint64
+	stores     atomic.Uint64
+	collisions atomic.Uint64
+}
+
+func sampled(zobristHash uint64) bool {
+	return zobristHash>>statsSampleShift == 0
+}
+
+func (s *stats) probe(zobristHash uint64, found bool) {
+	if !sampled(zobristHash) {
+		return
+	}
+	s.probes.Add(1)
+	if found {
+		s.hits.Add(1)
+	}
+}
+
+func (s *stats) store(zobristHash uint64, collision bool) {
+	if !sampled(zobristHash) {
+		return
+	}
+	s.stores.Add(1)
+	if collision {
+		s.collisions.Add(1)
+	}
+}
+
+func (s *stats) reset() {
+	s.probes.Store(0)
+	s.hits.Store(0)
+	s.stores.Store(0)
+	s.collisions.Store(0)
+}
+
+// Stats returns the statistics of the table since it was cleared.
+func (tt *TranspositionTable) Stats() Stats {
+	return Stats{
+		Probes:     tt.stats.probes.Load(),
+		Hits:       tt.stats.hits.Load(),
+		Stores:     tt.stats.stores.Load(),
+		Collisions: tt.stats.collisions.Load(),
+	}
+}
@@ -1,120 +1,253 @@
 package transpositiontable
 
 import (
+	"math"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/shaardie/clemens/pkg/evaluation"
 	"github.com/shaardie/clemens/pkg/move"
 )
 
-type nodeType uint8
+// NodeType describes the bound of the stored score.
+// The score of a PVNode is exact, of an AlphaNode an upper bound and of a BetaNode a lower bound.
+type NodeType uint8
 
 const (
-	PVNode nodeType = iota
+	PVNode NodeType = iota
 	AlphaNode
 	BetaNode
 )
 
-// 64MB
-const ttSizeInMB = 1024 * 1024 * 64
+// QuiescenceDepth is the depth of the entries of the quiescence search.
+// The main search enters the quiescence search at depth 0, so it never uses these entries for a cutoff,
+// but their best moves for the move ordering.
+const QuiescenceDepth uint8 = 0
 
-type bucket [bucketSize]ttEntry
+// NoStaticEval is the static evaluation of an entry, if the position was not evaluated, e.g. if in check.
+const NoStaticEval int16 = math.MinInt16
 
-const bucketSize = 4
+// sameEntryDepthMargin is the depth a new entry may be shallower than the entry of the same position from the current search.
+const sameEntryDepthMargin = 3
 
-const numberOfBuckets uint64 = ttSizeInMB / uint64(unsafe.Sizeof(bucket{}))
+// DefaultSizeInMB is the size of the table, if not resized.
+const DefaultSizeInMB = 64
 
-var tt = [numberOfBuckets]bucket{}
+// TranspositionTable is a table shared by all threads of a search.
+// Independent searches, e.g. different analyses in the same process, should use their own tables.
+type TranspositionTable struct {
+	buckets         []bucket
+	numberOfBuckets uint64
+	// generation is the generation of the current search, which is used to age the entries.
+	generation atomic.Uint32
+	stats      stats
+}
+
+// Default is the table for all searches, which do not need their own, e.g. the command line interface.
+var Default = New(DefaultSizeInMB)
+
+// New creates an empty table of the size in MB.
+func New(sizeInMB int) *TranspositionTable {
+	tt := &TranspositionTable{}
+	tt.generation.Store(1)
+	tt.Resize(sizeInMB)
+	return tt
+}
+
+// NewGeneration starts a new generation for the next search,
+// so the entries of the previous searches are replaced first.
+// It must not be called while a search is running.
+func (tt *TranspositionTable) NewGeneration() {
+	tt.generation.Store(tt.generation.Load()%generationCycle + 1)
+}
 
-var hashEntries uint64
+func (tt *TranspositionTable) getGeneration() uint8 {
+	return uint8(tt.generation.Load())
+}
 
-func HashFull() uint64 {
-	return 1000 * hashEntries / (numberOfBuckets * bucketSize)
+// hashFullSample is the number of entries used to estimate the usage of the table.
+const hashFullSample = 1000
+
+// HashFull returns the usage of the table by the current search in per mille.
+// It is estimated from the first entries of the table, like most engines do.
+func (tt *TranspositionTable) HashFull() uint64 {
+	generation := tt.getGeneration()
+	var used, sampled uint64
+	for i := range tt.buckets {
+		b := &tt.buckets[i]
+		for j := range bucketSize {
+			if sampled == hashFullSample {
+				return 1000 * used / sampled
+			}
+			sampled++
+			data := ttData(b.data[j].Load())
+			if !b.isEmpty(j) && data.getGeneration() == generation {
+				used++
+			}
+		}
+	}
+	return 1000 * used / sampled
 }
 
-func init() {
-	Reset()
+// Resize replaces the table with an empty one of the size in MB, if the size changes.
+// It must not be called while a search is running.
+func (tt *TranspositionTable) Resize(sizeInMB int) {
+	n := max(uint64(sizeInMB)*1024*1024/uint64(unsafe.Sizeof(bucket{})), 1)
+	if n == tt.numberOfBuckets {
+		return
+	}
+	tt.numberOfBuckets = n
+	// Drop the old table first, so the garbage collector is able to free it for the new one.
+	tt.buckets = nil
+	tt.buckets = make([]bucket, tt.numberOfBuckets)
+	tt.stats.reset()
 }
 
-func Reset() {
-	clear(tt[:])
+// Reset clears the table. It must not be called while a search is running.
+func (tt *TranspositionTable) Reset() {
+	clear(tt.buckets)
+	tt.stats.reset()
 }
 
-func Get(zobristHash uint64, alpha, beta int16, depth, ply uint8) (score int16, use bool, m move.Move) {
-	key := zobristHash % numberOfBuckets
-	var te *ttEntry
+// Entry is the content of the table for a position.
+type Entry struct {
+	BestMove move.Move
+	// Score is relative to the root, so mate scores are adjusted to the ply of the probe.
+	Score int16
+	// StaticEval is the static evaluation of the position without any correction or NoStaticEval.
+	StaticEval int16
+	Depth      uint8
+	NodeType   NodeType
+}
+
+// IsLowerBound returns true, if the real score is at least the score of the entry.
+func (e Entry) IsLowerBound() bool {
+	return e.NodeType == BetaNode || e.NodeType == PVNode
+}
+
+// IsUpperBound returns true, if the real score is at most the score of the entry.
+func (e Entry) IsUpperBound() bool {
+	return e.NodeType == AlphaNode || e.NodeType == PVNode
+}
+
+// Probe returns the entry of the position, if there is one.
+func (tt *TranspositionTable) Probe(zobristHash uint64, ply uint8) (Entry, bool) {
+	b := &tt.buckets[zobristHash%tt.numberOfBuckets]
+	var te ttData
 	var found bool
-	for i := range tt[key] {
-		te = &tt[key][i]
-		if te.zobristHash == zobristHash {
-			found = true
+	for i := range bucketSize {
+		te, found = b.load(i, zobristHash)
+		if found {
 			break
 		}
 	}
+	tt.stats.probe(zobristHash, found)
 
 	// No entry found
 	if !found {
-		return 0, false, move.NullMove
-	}
-
-	// Only use the value, if the depth of the entry is bigger that the current one.
-	// Remember that the depth decreases, while going down the tree.
-	// If we use this entry or not. The move should be a good guess.
-	if te.depth < depth {
-		return 0, false, te.bestMove
+		return Entry{}, false
 	}
 
-	score = te.score
+	score := te.getScore()
 
-	// // Adjust if mate value
+	// Adjust if mate value
 	if score > evaluation.INF-100 {
 		score -= int16(ply)
 	} else if score < -evaluation.INF+100 {
 		score += int16(ply)
 	}
 
-	switch te.getNodeType() {
+	return Entry{
+		BestMove:   te.getBestMove(),
+		Score:      score,
+		StaticEval: te.getStaticEval(),
+		Depth:      te.getDepth(),
+		NodeType:   te.getNodeType(),
+	}, true
+}
+
+// Get returns the score of the position, if it can be used for the window and the depth.
+// The best move is returned, whenever there is an entry, since it is a good guess for the move ordering.
+func (tt *TranspositionTable) Get(zobristHash uint64, alpha, beta int16, depth, ply uint8) (score int16, use bool, m move.Move) {
+	e, found := tt.Probe(zobristHash, ply)
+	if !found {
+		return 0, false, move.NullMove
+	}
+
+	score, use = e.Cutoff(alpha, beta, depth)
+	return score, use, e.BestMove
+}
+
+// Cutoff returns the score of the entry and true, if the score can be used for the window and the depth.
+func (e Entry) Cutoff(alpha, beta int16, depth uint8) (int16, bool) {
+	// Only use the value, if the depth of the entry is bigger that the current one.
+	// Remember that the depth decreases, while going down the tree.
+	if e.Depth < depth {
+		return 0, false
+	}
+
+	switch e.NodeType {
 	case AlphaNode:
-		if score <= alpha {
-			return alpha, true, te.bestMove
+		if e.Score <= alpha {
+			return alpha, true
 		}
 	case BetaNode:
-		if score >= beta {
-			return beta, true, te.bestMove
+		if e.Score >= beta {
+			return beta, true
 		}
 	case PVNode:
-		return score, true, te.bestMove
+		return e.Score, true
 	}
 
-	return score, false, te.bestMove
+	return e.Score, false
 }
 
 // PotentiallySave save the new transposition entry, if it is a better fit.
+// An entry of the same position is kept, if it is from the current search and a lot deeper.
+// Otherwise the entry with the lowest value by depth, age and bound is replaced.
 // Note, that we use single values as parameter for the case, so we not create the struct, if we do not have to
-func PotentiallySave(zobristHash uint64, bestMove move.Move, depth uint8, score int16, nt nodeType, age uint8) {
-	var te *ttEntry
-	key := zobristHash % numberOfBuckets
-	for i := range tt[key] {
-		te = &tt[key][i]
+func (tt *TranspositionTable) PotentiallySave(zobristHash uint64, bestMove move.Move, depth, ply uint8, score, staticEval int16, nt NodeType) {
+	// Mate values are saved relative to the position and not relative to the root,
+	// see https://www.chessprogramming.org/Transposition_Table#Mate_Scores
+	// A bound from a window of a shorter mate might exceed the range of the scores, so it is limited to it,
+	// which keeps it a valid bound.
+	if score > evaluation.INF-100 {
+		score = int16(min(int(score)+int(ply), int(evaluation.INF)))
+	} else if score < -evaluation.INF+100 {
+		score = int16(max(int(score)-int(ply), -int(evaluation.INF)))
+	}
 
+	generation := tt.getGeneration()
+	b := &tt.buckets[zobristHash%tt.numberOfBuckets]
+	replace := 0
+	replaceValue := math.MaxInt
+	for i := range bucketSize {
 		// Empty Entries should always be overriden
-		if te.zobristHash == 0 {
-			hashEntries++
+		if b.isEmpty(i) {
+			replace = i
 			break
 		}
 
-		// Found a worse one, replace
-		if te.depth <= depth && te.getAge() >= age {
-			break
+		data, found := b.load(i, zobristHash)
+		if found {
+			if nt != PVNode && data.getGeneration() == generation && int(depth)+sameEntryDepthMargin < int(data.getDepth()) {
+				return
+			}
+			// Keep the best move, if there is no new one
+			if bestMove == move.NullMove {
+				bestMove = data.getBestMove()
+			}
+			b.store(i, zobristHash, newTTData(bestMove, score, staticEval, depth, nt, generation))
+			return
 		}
 
-		// If none is found, we replace the last one
+		// Replace the entry with the lowest value, if there is no entry of the same position.
+		if v := data.replacementValue(generation); v < replaceValue {
+			replace = i
+			replaceValue = v
+		}
 	}
 
-	te.zobristHash = zobristHash
-	te.bestMove = bestMove
-	te.depth = depth
-	te.score = score
-	te.setNodeType(nt)
-	te.setAge(age)
+	tt.stats.store(zobristHash, !b.isEmpty(replace))
+	b.store(replace, zobristHash, newTTData(bestMove, score, staticEval, depth, nt, generation))
 }
@@ -0,0 +1,212 @@
+package transpositiontable
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResize(t *testing.T) {
+	tt := New(1)
+	assert.Equal(t, 1024*1024/64, len(tt.buckets))
+	assert.Equal(t, uintptr(64), unsafe.Sizeof(bucket{}))
+
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(28)
+	tt.PotentiallySave(1234, m, 5, 0, 42, 17, PVNode)
+	score, use, ttMove := tt.Get(1234, -100, 100, 5, 0)
+	assert.True(t, use)
+	assert.Equal(t, int16(42), score)
+	assert.Equal(t, m, ttMove)
+	assert.Equal(t, Stats{Probes: 1, Hits: 1, Stores: 1}, tt.Stats())
+
+	// Resizing to the same size keeps the entries
+	tt.Resize(1)
+	_, use, _ = tt.Get(1234, -100, 100, 5, 0)
+	assert.True(t, use)
+
+	tt.Reset()
+	assert.Equal(t, uint64(0), tt.HashFull())
+	assert.Equal(t, Stats{}, tt.Stats())
+	_, use, ttMove = tt.Get(1234, -100, 100, 5, 0)
+	assert.False(t, use)
+	assert.Equal(t, move.NullMove, ttMove)
+
+	tt.Resize(2)
+	assert.Equal(t, 2*1024*1024/64, len(tt.buckets))
+}
+
+func TestIndependentTables(t *testing.T) {
+	tt1 := New(1)
+	tt2 := New(1)
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(28)
+	tt1.PotentiallySave(1234, m, 5, 0, 42, 17, PVNode)
+
+	_, use, _ := tt1.Get(1234, -100, 100, 5, 0)
+	assert.True(t, use)
+	_, use, ttMove := tt2.Get(1234, -100, 100, 5, 0)
+	assert.False(t, use)
+	assert.Equal(t, move.NullMove, ttMove)
+}
+
+func TestProbe(t *testing.T) {
+	tt := New(1)
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(28)
+
+	_, found := tt.Probe(1234, 0)
+	assert.False(t, found)
+
+	// Mate scores are stored relative to the position
+	tt.PotentiallySave(1234, m, 7, 3, evaluation.INF-10, 17, BetaNode)
+	e, found := tt.Probe(1234, 5)
+	assert.True(t, found)
+	assert.Equal(t, Entry{BestMove: m, Score: evaluation.INF - 12, StaticEval: 17, Depth: 7, NodeType: BetaNode}, e)
+	assert.True(t, e.IsLowerBound())
+	assert.False(t, e.IsUpperBound())
+
+	// Bounds beyond the mate of the position are limited to the range of the scores
+	tt.PotentiallySave(5678, m, 0, 13, evaluation.INF-12, 17, AlphaNode)
+	e, found = tt.Probe(5678, 13)
+	assert.True(t, found)
+	assert.Equal(t, evaluation.INF-13, e.Score)
+	tt.PotentiallySave(9012, m, 0, 13, -evaluation.INF+12, 17, BetaNode)
+	e, found = tt.Probe(9012, 13)
+	assert.True(t, found)
+	assert.Equal(t, -evaluation.INF+13, e.Score)
+}
+
+func TestEntry_Cutoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     Entry
+		depth     uint8
+		wantScore int16
+		wantUse   bool
+	}{
+		{
+			name:  "too shallow",
+			entry: Entry{Score: 42, Depth: 4, NodeType: PVNode},
+			depth: 5,
+		},
+		{
+			name:      "exact",
+			entry:     Entry{Score: 42, Depth: 5, NodeType: PVNode},
+			depth:     5,
+			wantScore: 42,
+			wantUse:   true,
+		},
+		{
+			name:      "upper bound below alpha",
+			entry:     Entry{Score: -200, Depth: 6, NodeType: AlphaNode},
+			depth:     5,
+			wantScore: -100,
+			wantUse:   true,
+		},
+		{
+			name:      "upper bound inside the window",
+			entry:     Entry{Score: 50, Depth: 6, NodeType: AlphaNode},
+			depth:     5,
+			wantScore: 50,
+		},
+		{
+			name:      "lower bound above beta",
+			entry:     Entry{Score: 200, Depth: 6, NodeType: BetaNode},
+			depth:     5,
+			wantScore: 100,
+			wantUse:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, use := tt.entry.Cutoff(-100, 100, tt.depth)
+			assert.Equal(t, tt.wantScore, score)
+			assert.Equal(t, tt.wantUse, use)
+		})
+	}
+}
+
+func TestHashFull(t *testing.T) {
+	tt := New(1)
+	tt.NewGeneration()
+	for hash := range uint64(100) {
+		tt.PotentiallySave(hash, move.NullMove, 1, 0, 0, 0, PVNode)
+	}
+	assert.Equal(t, uint64(100), tt.HashFull())
+
+	// Only the entries of the current search are counted
+	tt.NewGeneration()
+	assert.Equal(t, uint64(0), tt.HashFull())
+}
+
+func TestPotentiallySave(t *testing.T) {
+	var m1, m2 move.Move
+	m1.SetSourceSquare(12).SetTargetSquare(28)
+	m2.SetSourceSquare(6).SetTargetSquare(21)
+	// All hashes are in the same bucket, but differ in the upper half, which is checked by the key.
+	n := uint64(1) << 32
+
+	t.Run("same position", func(t *testing.T) {
+		tt := New(1)
+		tt.PotentiallySave(1, m1, 10, 0, 42, 17, AlphaNode)
+
+		// Much shallower entries of the current search are ignored
+		tt.PotentiallySave(1, m2, 5, 0, 50, 17, BetaNode)
+		e, _ := tt.Probe(1, 0)
+		assert.Equal(t, Entry{BestMove: m1, Score: 42, StaticEval: 17, Depth: 10, NodeType: AlphaNode}, e)
+
+		// The best move is kept, if there is no new one
+		tt.PotentiallySave(1, move.NullMove, 8, 0, 50, 17, BetaNode)
+		e, _ = tt.Probe(1, 0)
+		assert.Equal(t, Entry{BestMove: m1, Score: 50, StaticEval: 17, Depth: 8, NodeType: BetaNode}, e)
+
+		// Entries of previous searches are always replaced
+		tt.NewGeneration()
+		tt.PotentiallySave(1, m2, 1, 0, 60, NoStaticEval, AlphaNode)
+		e, _ = tt.Probe(1, 0)
+		assert.Equal(t, Entry{BestMove: m2, Score: 60, StaticEval: NoStaticEval, Depth: 1, NodeType: AlphaNode}, e)
+	})
+
+	t.Run("replacement", func(t *testing.T) {
+		tt := New(1)
+		require.Zero(t, n%tt.numberOfBuckets)
+		// Fill the bucket with entries of different depths
+		for i := range uint64(bucketSize) {
+			tt.PotentiallySave(1+i*n, m1, uint8(10+i), 0, 0, 0, AlphaNode)
+		}
+		// The shallowest entry is replaced
+		tt.PotentiallySave(1+bucketSize*n, m1, 1, 0, 0, 0, AlphaNode)
+		_, found := tt.Probe(1, 0)
+		assert.False(t, found)
+		_, found = tt.Probe(1+bucketSize*n, 0)
+		assert.True(t, found)
+
+		// Old entries are replaced before deep ones
+		tt.NewGeneration()
+		tt.PotentiallySave(1+n, m1, 30, 0, 0, 0, AlphaNode)
+		tt.NewGeneration()
+		tt.PotentiallySave(1+(bucketSize+1)*n, m1, 1, 0, 0, 0, AlphaNode)
+		_, found = tt.Probe(1+n, 0)
+		assert.True(t, found)
+		_, found = tt.Probe(1+(bucketSize+1)*n, 0)
+		assert.True(t, found)
+	})
+}
+
+func TestStats(t *testing.T) {
+	assert.Equal(t, 0.0, Stats{}.HitRate())
+	assert.Equal(t, 0.0, Stats{}.CollisionRate())
+	assert.Equal(t, 0.25, Stats{Probes: 4, Hits: 1}.HitRate())
+	assert.Equal(t, 0.5, Stats{Stores: 4, Collisions: 2}.CollisionRate())
+
+	// Only positions with the upper bits of the hash set to zero are counted
+	tt := New(1)
+	tt.Probe(1, 0)
+	tt.Probe(1<<63, 0)
+	assert.Equal(t, Stats{Probes: 1}, tt.Stats())
+}
@@ -1,27 +1,118 @@
 package transpositiontable
 
-import "github.com/shaardie/clemens/pkg/move"
+import (
+	"sync/atomic"
 
-type ttEntry struct {
-	zobristHash uint64
-	bestMove    move.Move
-	score       int16
-	depth       uint8
+	"github.com/shaardie/clemens/pkg/move"
+)
 
-	// 0-1 for the Node Type
-	// 2-7 for the Age
-	ageAndNodeType uint8
+// bucket contains the entries for all positions with the same index.
+// The table is shared between all search threads, so every entry is stored in two words,
+// which are read and written atomically.
+// The key is the upper half of the zobrist hash xored with both halves of the data,
+// so an entry torn by two concurrently writing threads does not match the hash anymore.
+// Only the upper half of the zobrist hash is verified by the key.
+// The index of the bucket is the hash modulo the number of buckets, which does not fix the lower half,
+// so rarely a position finds the entry of another position with the same upper half.
+// The search never plays the best move of an entry without generating it for the position.
+// See https://www.chessprogramming.org/Shared_Hash_Table#Lockless
+// An entry uses 12 bytes, so a bucket of 5 entries has the size of a cache line of 64 bytes.
+// The buckets are not aligned to the cache lines, so a bucket might still span two of them.
+type bucket struct {
+	data [bucketSize]atomic.Uint64
+	keys [bucketSize]atomic.Uint32
+	_    uint32
 }
 
-func (te *ttEntry) getNodeType() nodeType {
-	return nodeType(te.ageAndNodeType & 0b11)
+const bucketSize = 5
+
+// ttData is the content of an entry
+// 0-15 for the best move without its score
+// 16-31 for the score
+// 32-47 for the static evaluation
+// 48-55 for the depth
+// 56-57 for the Node Type
+// 58-63 for the Generation
+type ttData uint64
+
+const (
+	// generationMask is the mask of the 6 bits of the generation.
+	generationMask = 0b111111
+	// generationCycle is the number of generations, which are 1 to 63,
+	// so the data of an entry is never zero and an empty entry is never found.
+	generationCycle = generationMask
+)
+
+func newTTData(bestMove move.Move, score, staticEval int16, depth uint8, nt NodeType, generation uint8) ttData {
+	return ttData(uint16(bestMove)) |
+		ttData(uint16(score))<<16 |
+		ttData(uint16(staticEval))<<32 |
+		ttData(depth)<<48 |
+		ttData(nt&0b11)<<56 |
+		ttData(generation&generationMask)<<58
+}
+
+func (d ttData) getBestMove() move.Move {
+	return move.Move(uint16(d))
+}
+
+func (d ttData) getScore() int16 {
+	return int16(d >> 16)
+}
+
+func (d ttData) getStaticEval() int16 {
+	return int16(d >> 32)
+}
+
+// withoutStaticEval returns the data with NoStaticEval as static evaluation.
+func (d ttData) withoutStaticEval() ttData {
+	return newTTData(d.getBestMove(), d.getScore(), NoStaticEval, d.getDepth(), d.getNodeType(), d.getGeneration())
+}
+
+func (d ttData) getDepth() uint8 {
+	return uint8(d >> 48)
 }
-func (te *ttEntry) setNodeType(nt nodeType) {
-	te.ageAndNodeType = te.ageAndNodeType&0b11111100 | uint8(nt)
+
+func (d ttData) getNodeType() NodeType {
+	return NodeType(d >> 56 & 0b11)
+}
+
+func (d ttData) getGeneration() uint8 {
+	return uint8(d >> 58)
 }
-func (te *ttEntry) getAge() uint8 {
-	return te.ageAndNodeType >> 2
+
+// relativeAge returns the number of searches since the entry was stored.
+func (d ttData) relativeAge(generation uint8) uint8 {
+	return (generationCycle + generation - d.getGeneration()) % generationCycle
 }
-func (te *ttEntry) setAge(age uint8) {
-	te.ageAndNodeType = te.ageAndNodeType&0b11 | (age << 2)
+
+// replacementValue returns the worth of keeping the entry.
+// Deep entries of recent searches with an exact score are the most valuable ones.
+func (d ttData) replacementValue(generation uint8) int {
+	value := int(d.getDepth()) - 8*int(d.relativeAge(generation))
+	if d.getNodeType() == PVNode {
+		value += 2
+	}
+	return value
+}
+
+// ttKey returns the key of the entry for the zobrist hash and the data.
+func ttKey(zobristHash uint64, data ttData) uint32 {
+	return uint32(zobristHash>>32) ^ uint32(data) ^ uint32(data>>32)
+}
+
+// load returns the data of the entry and if the entry belongs to the zobrist hash.
+func (b *bucket) load(i int, zobristHash uint64) (ttData, bool) {
+	data := ttData(b.data[i].Load())
+	key := b.keys[i].Load()
+	return data, data != 0 && key == ttKey(zobristHash, data)
+}
+
+func (b *bucket) store(i int, zobristHash uint64, data ttData) {
+	b.keys[i].Store(ttKey(zobristHash, data))
+	b.data[i].Store(uint64(data))
+}
+
+func (b *bucket) isEmpty(i int) bool {
+	return b.data[i].Load() == 0
 }
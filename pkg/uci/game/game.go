@@ -2,26 +2,52 @@ package game
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/shaardie/clemens/pkg/evaluation"
 	"github.com/shaardie/clemens/pkg/position"
 	"github.com/shaardie/clemens/pkg/search"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+	"github.com/shaardie/clemens/pkg/uci/option"
 	"github.com/shaardie/clemens/pkg/uci/state"
 )
 
 type Game interface {
 	IsReady()
+	NewGame()
 	NewPosition(tokens []string)
 	StartSearch(tokens []string)
 	StopSearch()
+	PonderHit()
+	SetOption(tokens []string)
+	PrintOptions()
 	private()
 }
 
 const (
-	infoChannelSize = 16
+	maxThreads      = 256
+	maxMultiPV      = 256
+	maxNodesTime    = 10000
+	maxContempt     = 1000
+	maxHashInMB     = 65536
+	maxMoveOverhead = 5000
+	maxSkillSeed    = math.MaxInt32
+)
+
+// defaultHashFile is the default of the UCI option `HashFile` to save and load the transposition table.
+const defaultHashFile = "clemens.hash"
+
+// Names of the evaluators for the UCI option `Evaluator`.
+const (
+	handCraftedEvaluator = "HandCrafted"
+	materialEvaluator    = "Material"
 )
 
 type gameImpl struct {
@@ -29,22 +55,45 @@ type gameImpl struct {
 	state        state.State
 	maxTimeInMs  int
 	maxDepth     uint8
-	info         chan search.Info
+	reporter     search.Reporter
+	tables       search.Tables
 	search       *search.Search
 	searchCancel context.CancelFunc
+	threads      int
+	multiPV      int
+	nodesTime    int
+	moveOverhead int
+	// limitStrength uses the elo instead of the skillLevel to weaken the search.
+	limitStrength bool
+	elo           int
+	skillLevel    int
+	skillSeed     int
+	showWDL       bool
+	evaluator     string
+	// hashFile is the file to save the transposition table to and to load it from.
+	hashFile string
+	// disabledPruning are the pruning techniques switched off, e.g. for a SPRT.
+	disabledPruning   search.Pruning
+	correctionHistory bool
+	pondering         bool
+	options           *option.Registry
 }
 
 func New() Game {
 	return newGameImpl()
 }
 func newGameImpl() *gameImpl {
-	return &gameImpl{
+	g := &gameImpl{
 		isWorking:   &sync.Mutex{},
 		state:       state.New(),
 		maxTimeInMs: 5000,
 		maxDepth:    6,
-		info:        make(chan search.Info, infoChannelSize),
+		reporter:    newUCIReporter(os.Stdout),
+		// The command line interface runs only a single game, so it uses the default tables.
+		tables: search.DefaultTables,
 	}
+	g.options = g.newOptions()
+	return g
 }
 
 func (g *gameImpl) private() {}
@@ -55,6 +104,60 @@ func (g *gameImpl) IsReady() {
 	fmt.Println("readyok")
 }
 
+// NewGame resets the game, but keeps the options.
+func (g *gameImpl) NewGame() {
+	g.isWorking.Lock()
+	defer g.isWorking.Unlock()
+
+	if g.state.Get() == state.RUNNING {
+		fmt.Println("info string wrong idle state to start a new game")
+		return
+	}
+	g.search = nil
+	g.clearHash()
+	g.state.Set(state.IDLE)
+}
+
+// clearHash clears the transposition table and the evaluation cache.
+func (g *gameImpl) clearHash() {
+	g.tables.TT.Reset()
+	g.tables.EvalCache.Clear()
+}
+
+// saveHash writes the transposition table to the hash file,
+// so the analysis is able to continue later with the same table.
+func (g *gameImpl) saveHash() {
+	f, err := os.Create(g.hashFile)
+	if err != nil {
+		fmt.Printf("info string unable to save hash, %v\n", err)
+		return
+	}
+	err = g.tables.TT.Save(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf("info string unable to save hash, %v\n", err)
+		return
+	}
+	fmt.Printf("info string hash saved to %v\n", g.hashFile)
+}
+
+// loadHash replaces the transposition table with the one from the hash file.
+func (g *gameImpl) loadHash() {
+	f, err := os.Open(g.hashFile)
+	if err != nil {
+		fmt.Printf("info string unable to load hash, %v\n", err)
+		return
+	}
+	defer f.Close()
+	if err := g.tables.TT.Load(f); err != nil {
+		fmt.Printf("info string unable to load hash, %v\n", err)
+		return
+	}
+	fmt.Printf("info string hash loaded from %v\n", g.hashFile)
+}
+
 func (g *gameImpl) NewPosition(tokens []string) {
 	g.isWorking.Lock()
 	defer g.isWorking.Unlock()
@@ -89,22 +192,33 @@ func (g *gameImpl) NewPosition(tokens []string) {
 		pos = fenPos
 		g.state.Set(state.POSITION_SET)
 		tokens = tokens[7:]
-	}
-	g.search = search.NewSearch(*pos)
-	if len(tokens) <= 1 || tokens[0] != "moves" {
+	default:
+		fmt.Printf("info string unknown position %v\n", tokens[0])
 		return
 	}
-	tokens = tokens[1:]
-	for _, token := range tokens {
-		err := g.search.MakeMoveFromString(token)
-		if err != nil {
-			fmt.Printf("info string error while making move %v, %v \n", token, err)
-			return
-		}
+	var moves []string
+	if len(tokens) > 1 && tokens[0] == "moves" {
+		moves = tokens[1:]
+	}
+
+	// The search is kept during the game, so it can reuse its state, if the position extends the previous one.
+	if g.search == nil {
+		g.search = search.NewSearch(*pos, g.tables)
+		g.search.Reporter = g.reporter
 	}
+	if _, err := g.search.SetPosition(*pos, moves); err != nil {
+		fmt.Printf("info string error while making %v\n", err)
+	}
+}
+
+// goCommands are the tokens of the go command, which are no parameter of a previous token.
+var goCommands = []string{
+	"searchmoves", "ponder", "wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime", "infinite",
 }
 
-func parseGo(tokens []string) (sp search.SearchParameter) {
+// parseGo parses the tokens of the go command.
+// The position is needed to parse the moves of the searchmoves token.
+func parseGo(pos *position.Position, tokens []string) (sp search.SearchParameter) {
 	var err error
 
 	if len(tokens) == 0 {
@@ -116,8 +230,15 @@ func parseGo(tokens []string) (sp search.SearchParameter) {
 		tokens = tokens[1:]
 		switch t {
 		case "searchmoves":
-			fmt.Println("info string searchmoves not implemented")
-			return sp
+			for len(tokens) > 0 && !slices.Contains(goCommands, tokens[0]) {
+				m, err := pos.MoveFromString(tokens[0])
+				if err != nil {
+					fmt.Printf("info string search move %v broken, %v\n", tokens[0], err)
+					return
+				}
+				sp.SearchMoves = append(sp.SearchMoves, m)
+				tokens = tokens[1:]
+			}
 		case "wtime":
 			if len(tokens) == 0 {
 				fmt.Println("info string white time missing")
@@ -201,28 +322,27 @@ func parseGo(tokens []string) (sp search.SearchParameter) {
 				fmt.Println("info string nodes missing")
 				return
 			}
-			_, err = strconv.Atoi(tokens[0])
+			sp.Nodes, err = strconv.ParseUint(tokens[0], 10, 64)
 			if err != nil {
 				fmt.Printf("info string nodes broken, %v\n", err)
 				return
 			}
 			tokens = tokens[1:]
-			fmt.Println("info string nodes limit not implemented")
 		case "mate":
 			if len(tokens) == 0 {
 				fmt.Println("info string mate missing")
 				return
 			}
-			_, err = strconv.Atoi(tokens[0])
+			sp.Mate, err = strconv.Atoi(tokens[0])
 			if err != nil {
 				fmt.Printf("info string mate broken, %v\n", err)
 				return
 			}
 			tokens = tokens[1:]
-			fmt.Println("info string mate not implemented")
+		case "ponder":
+			sp.Ponder = true
 		case "infinite":
 			sp.Infinite = true
-			tokens = tokens[1:]
 		default:
 			fmt.Printf("info string unknown go command %v\n", t)
 			return
@@ -241,15 +361,31 @@ func (g *gameImpl) StartSearch(tokens []string) {
 	}
 
 	// Create Search with the correct properties
-	gp := parseGo(tokens)
+	gp := parseGo(&g.search.Pos, tokens)
+	gp.Threads = g.threads
+	gp.MultiPV = g.multiPV
+	gp.NodesTime = g.nodesTime
+	gp.MoveOverhead = g.moveOverhead
+	gp.SkillLevel = g.skillLevel
+	if g.limitStrength {
+		gp.SkillLevel = search.EloToSkillLevel(g.elo)
+	}
+	gp.LimitStrength = gp.SkillLevel < search.MaxSkillLevel
+	gp.SkillSeed = uint64(g.skillSeed)
+	gp.ShowWDL = g.showWDL
+	gp.DisabledPruning = g.disabledPruning
+	gp.CorrectionHistory = g.correctionHistory
+	g.pondering = gp.Ponder
+	g.search.Evaluator = g.newEvaluator()
 	ctx, cancel := context.WithCancel(context.Background())
 	g.searchCancel = cancel
+	// The state is set before the search starts, since the search might finish immediately.
+	g.state.Set(state.RUNNING)
 	go func() {
 		defer cancel()
-		fmt.Printf("bestmove %v\n", g.search.Search(ctx, gp))
+		g.search.Search(ctx, gp)
 		g.state.Set(state.IDLE)
 	}()
-	g.state.Set(state.RUNNING)
 }
 func (g *gameImpl) StopSearch() {
 	g.isWorking.Lock()
@@ -260,3 +396,124 @@ func (g *gameImpl) StopSearch() {
 	}
 	g.searchCancel()
 }
+
+// PonderHit signals the running search, that the opponent played the expected move.
+func (g *gameImpl) PonderHit() {
+	g.isWorking.Lock()
+	defer g.isWorking.Unlock()
+
+	if g.state.Get() != state.RUNNING || !g.pondering {
+		return
+	}
+	g.pondering = false
+	g.search.PonderHit()
+}
+
+// newOptions creates all UCI options of the game.
+func (g *gameImpl) newOptions() *option.Registry {
+	r := option.NewRegistry()
+	r.Add(
+		option.NewSpin("Threads", 1, 1, maxThreads, func(v int) { g.threads = v }),
+		option.NewSpin("Hash", transpositiontable.DefaultSizeInMB, 1, maxHashInMB, g.tables.TT.Resize),
+		option.NewSpin("EvalHash", evaluation.DefaultCacheSizeInMB, 1, maxHashInMB, g.tables.EvalCache.Resize),
+		option.NewButton("Clear Hash", g.clearHash),
+		option.NewString("HashFile", defaultHashFile, func(v string) { g.hashFile = v }),
+		option.NewButton("Save Hash", g.saveHash),
+		option.NewButton("Load Hash", g.loadHash),
+		option.NewSpin("MultiPV", 1, 1, maxMultiPV, func(v int) { g.multiPV = v }),
+		// Pondering is controlled by the GUI with `go ponder`, so the option has no effect.
+		option.NewCheck("Ponder", false, func(bool) {}),
+		option.NewSpin("Contempt", int(evaluation.DefaultContempt), -maxContempt, maxContempt, func(v int) { evaluation.SetContempt(int16(v)) }),
+		option.NewSpin("nodestime", 0, 0, maxNodesTime, func(v int) { g.nodesTime = v }),
+		option.NewSpin("Move Overhead", search.DefaultMoveOverhead, 0, maxMoveOverhead, func(v int) { g.moveOverhead = v }),
+		option.NewCheck("UCI_LimitStrength", false, func(v bool) { g.limitStrength = v }),
+		// The rating is an approximate strength scale, which is not calibrated against real Elo ratings yet.
+		option.NewSpin("UCI_Elo", search.MaxElo, search.MinElo, search.MaxElo, func(v int) { g.elo = v }),
+		option.NewSpin("Skill Level", search.MaxSkillLevel, 0, search.MaxSkillLevel, func(v int) { g.skillLevel = v }),
+		// The seed makes the random decisions of a weakened search reproducible.
+		option.NewSpin("Skill Seed", 0, 0, maxSkillSeed, func(v int) { g.skillSeed = v }),
+		option.NewCheck("UCI_ShowWDL", false, func(v bool) { g.showWDL = v }),
+		option.NewCombo("Evaluator", handCraftedEvaluator, []string{handCraftedEvaluator, materialEvaluator}, g.setEvaluator),
+		g.newPruningOption("LateMovePruning", search.LateMovePruning),
+		g.newPruningOption("SEEPruning", search.SEEPruning),
+		g.newPruningOption("Razoring", search.Razoring),
+		g.newPruningOption("InternalIterativeReduction", search.InternalIterativeReduction),
+		g.newPruningOption("QuiescenceChecks", search.QuiescenceChecks),
+		// The correction history is not verified by a SPRT yet.
+		option.NewCheck("CorrectionHistory", false, func(v bool) { g.correctionHistory = v }),
+	)
+	return r
+}
+
+// newPruningOption creates an option, which enables or disables the pruning technique.
+func (g *gameImpl) newPruningOption(name string, p search.Pruning) *option.Option {
+	return option.NewCheck(name, true, func(v bool) {
+		if v {
+			g.disabledPruning &^= p
+		} else {
+			g.disabledPruning |= p
+		}
+	})
+}
+
+// setEvaluator selects the evaluator and clears the transposition table,
+// since its static evaluations belong to the previous evaluator.
+func (g *gameImpl) setEvaluator(v string) {
+	if g.evaluator != "" && g.evaluator != v {
+		g.tables.TT.Reset()
+	}
+	g.evaluator = v
+}
+
+// newEvaluator creates the evaluator selected by the UCI option `Evaluator`.
+func (g *gameImpl) newEvaluator() search.Evaluator {
+	if g.evaluator == materialEvaluator {
+		return evaluation.Material{}
+	}
+	return evaluation.NewHandCrafted(g.tables.EvalCache)
+}
+
+func (g *gameImpl) PrintOptions() {
+	fmt.Println(g.options)
+}
+
+func (g *gameImpl) SetOption(tokens []string) {
+	g.isWorking.Lock()
+	defer g.isWorking.Unlock()
+
+	if g.state.Get() == state.RUNNING {
+		fmt.Println("info string options can not be set while searching")
+		return
+	}
+
+	name, value, err := parseSetOption(tokens)
+	if err != nil {
+		fmt.Printf("info string broken setoption command, %v\n", err)
+		return
+	}
+
+	err = g.options.Set(name, value)
+	if err != nil {
+		fmt.Printf("info string option %v not set, %v\n", name, err)
+	}
+}
+
+// parseSetOption parses the tokens of the setoption command in the form `name <id> [value <x>]`.
+// Both the name and the value may contain spaces.
+func parseSetOption(tokens []string) (name string, value string, err error) {
+	if len(tokens) == 0 || tokens[0] != "name" {
+		return "", "", errors.New("name missing")
+	}
+	tokens = tokens[1:]
+	idx := slices.Index(tokens, "value")
+	if idx < 0 {
+		name = strings.Join(tokens, " ")
+	} else {
+		name = strings.Join(tokens[:idx], " ")
+		value = strings.Join(tokens[idx+1:], " ")
+	}
+	if name == "" {
+		return "", "", errors.New("name missing")
+	}
+	return name, value, nil
+}
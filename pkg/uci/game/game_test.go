@@ -1,11 +1,21 @@
 package game
 
 import (
+	"bytes"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/position"
 	"github.com/shaardie/clemens/pkg/search"
+	"github.com/shaardie/clemens/pkg/search/transpositiontable"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/shaardie/clemens/pkg/uci/state"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_game_newPosition(t *testing.T) {
@@ -54,6 +64,46 @@ func Test_game_newPosition(t *testing.T) {
 	}
 }
 
+func Test_game_newPositionReusesSearch(t *testing.T) {
+	g := newGameImpl()
+	g.NewPosition(strings.Split("startpos moves e2e4", " "))
+	s := g.search
+
+	// The game continues, so the search is kept
+	g.NewPosition(strings.Split("startpos moves e2e4 e7e5 g1f3", " "))
+	assert.Same(t, s, g.search)
+	assert.Equal(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2", g.search.Pos.ToFen())
+
+	// Another position with the same search, but a reset state
+	g.NewPosition(strings.Split("startpos moves d2d4", " "))
+	assert.Same(t, s, g.search)
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1", g.search.Pos.ToFen())
+
+	// A new game creates a new search
+	g.NewGame()
+	g.NewPosition(strings.Split("startpos", " "))
+	assert.NotSame(t, s, g.search)
+}
+
+func Test_game_StartSearchWithoutLegalSearchMoves(t *testing.T) {
+	g := newGameImpl()
+	var b bytes.Buffer
+	g.reporter = newUCIReporter(&b)
+	isIdle := func() bool { return g.state.Get() == state.IDLE }
+
+	// The search returns immediately, but the game is not stuck in the running state
+	g.NewPosition(strings.Split("startpos", " "))
+	g.StartSearch(strings.Split("searchmoves a1a1", " "))
+	require.Eventually(t, isIdle, time.Second, time.Millisecond)
+	assert.Contains(t, b.String(), "bestmove 0000\n")
+
+	g.NewPosition(strings.Split("startpos", " "))
+	g.StartSearch(strings.Split("depth 2", " "))
+	require.Eventually(t, isIdle, time.Second, time.Millisecond)
+	assert.Equal(t, 2, strings.Count(b.String(), "bestmove "))
+	assert.NotContains(t, strings.SplitAfter(b.String(), "bestmove 0000\n")[1], "bestmove 0000")
+}
+
 func Test_parseGo(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -69,10 +119,171 @@ func Test_parseGo(t *testing.T) {
 				MovesToGo: 35,
 			},
 		},
+		{
+			name:   "nodes",
+			tokens: strings.Split("nodes 100000", " "),
+			want: search.SearchParameter{
+				Nodes: 100000,
+			},
+		},
+		{
+			name:   "mate",
+			tokens: strings.Split("mate 3", " "),
+			want: search.SearchParameter{
+				Mate: 3,
+			},
+		},
+		{
+			name:   "ponder",
+			tokens: strings.Split("ponder wtime 1000 btime 2000", " "),
+			want: search.SearchParameter{
+				Ponder: true,
+				WTime:  1000,
+				BTime:  2000,
+			},
+		},
+		{
+			name:   "infinite",
+			tokens: strings.Split("infinite", " "),
+			want: search.SearchParameter{
+				Infinite: true,
+			},
+		},
+		{
+			name:   "searchmoves followed by other parameters",
+			tokens: strings.Split("searchmoves e2e4 g1f3 wtime 1000 depth 5", " "),
+			want: search.SearchParameter{
+				SearchMoves: []move.Move{
+					*new(move.Move).SetSourceSquare(types.SQUARE_E2).SetTargetSquare(types.SQUARE_E4),
+					*new(move.Move).SetSourceSquare(types.SQUARE_G1).SetTargetSquare(types.SQUARE_F3),
+				},
+				WTime: 1000,
+				Depth: 5,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseGo(position.New(), tt.tokens))
+		})
+	}
+}
+
+func Test_parseSetOption(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokens    []string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{
+			name:      "spin",
+			tokens:    strings.Split("name Threads value 4", " "),
+			wantName:  "Threads",
+			wantValue: "4",
+		},
+		{
+			name:     "button with spaces",
+			tokens:   strings.Split("name Clear Hash", " "),
+			wantName: "Clear Hash",
+		},
+		{
+			name:    "missing name",
+			tokens:  strings.Split("value 4", " "),
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, parseGo(tt.tokens))
+			name, value, err := parseSetOption(tt.tokens)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantValue, value)
 		})
 	}
 }
+
+func Test_game_SetOption(t *testing.T) {
+	g := newGameImpl()
+	assert.Equal(t, 1, g.threads)
+	g.SetOption(strings.Split("name Threads value 4", " "))
+	assert.Equal(t, 4, g.threads)
+	g.SetOption(strings.Split("name Threads value 100000", " "))
+	assert.Equal(t, 4, g.threads)
+	g.SetOption(strings.Split("name multipv value 3", " "))
+	assert.Equal(t, 3, g.multiPV)
+	assert.Equal(t, search.DefaultMoveOverhead, g.moveOverhead)
+	g.SetOption(strings.Split("name Move Overhead value 100", " "))
+	assert.Equal(t, 100, g.moveOverhead)
+	assert.Equal(t, search.MaxSkillLevel, g.skillLevel)
+	assert.Equal(t, search.MaxElo, g.elo)
+	g.SetOption(strings.Split("name UCI_LimitStrength value true", " "))
+	g.SetOption(strings.Split("name UCI_Elo value 1500", " "))
+	g.SetOption(strings.Split("name Skill Seed value 3", " "))
+	assert.True(t, g.limitStrength)
+	assert.Equal(t, 1500, g.elo)
+	assert.Equal(t, 3, g.skillSeed)
+	assert.False(t, g.showWDL)
+	g.SetOption(strings.Split("name UCI_ShowWDL value true", " "))
+	assert.True(t, g.showWDL)
+	assert.IsType(t, evaluation.HandCrafted{}, g.newEvaluator())
+	// The static evaluations of the previous evaluator are removed
+	g.tables = search.NewTables(1, 1)
+	g.tables.TT.PotentiallySave(1234, move.NullMove, 5, 0, 42, 17, transpositiontable.PVNode)
+	g.SetOption(strings.Split("name Evaluator value HandCrafted", " "))
+	_, found := g.tables.TT.Probe(1234, 0)
+	assert.True(t, found)
+	g.SetOption(strings.Split("name Evaluator value material", " "))
+	assert.Equal(t, materialEvaluator, g.evaluator)
+	assert.IsType(t, evaluation.Material{}, g.newEvaluator())
+	_, found = g.tables.TT.Probe(1234, 0)
+	assert.False(t, found)
+
+	assert.False(t, g.correctionHistory)
+	g.SetOption(strings.Split("name CorrectionHistory value true", " "))
+	assert.True(t, g.correctionHistory)
+	assert.Equal(t, search.Pruning(0), g.disabledPruning)
+	g.SetOption(strings.Split("name Razoring value false", " "))
+	g.SetOption(strings.Split("name LateMovePruning value false", " "))
+	assert.Equal(t, search.Razoring|search.LateMovePruning, g.disabledPruning)
+	g.SetOption(strings.Split("name Razoring value true", " "))
+	assert.Equal(t, search.LateMovePruning, g.disabledPruning)
+	g.SetOption(strings.Split("name QuiescenceChecks value false", " "))
+	assert.Equal(t, search.LateMovePruning|search.QuiescenceChecks, g.disabledPruning)
+}
+
+func Test_game_saveLoadHash(t *testing.T) {
+	g := newGameImpl()
+	g.tables = search.NewTables(1, 1)
+	assert.Equal(t, defaultHashFile, g.hashFile)
+	file := filepath.Join(t.TempDir(), "analysis hash")
+	g.SetOption(strings.Split("name HashFile value "+file, " "))
+	assert.Equal(t, file, g.hashFile)
+
+	var m move.Move
+	m.SetSourceSquare(12).SetTargetSquare(28)
+	g.tables.TT.PotentiallySave(1234, m, 5, 0, 42, 17, transpositiontable.PVNode)
+	g.SetOption(strings.Split("name Save Hash", " "))
+
+	g.SetOption(strings.Split("name Clear Hash", " "))
+	_, found := g.tables.TT.Probe(1234, 0)
+	assert.False(t, found)
+
+	g.SetOption(strings.Split("name Load Hash", " "))
+	e, found := g.tables.TT.Probe(1234, 0)
+	assert.True(t, found)
+	assert.Equal(t, m, e.BestMove)
+	assert.Equal(t, int16(42), e.Score)
+	assert.Equal(t, transpositiontable.NoStaticEval, e.StaticEval)
+
+	// A missing file keeps the table
+	g.SetOption(strings.Split("name HashFile value "+filepath.Join(t.TempDir(), "missing"), " "))
+	g.SetOption(strings.Split("name Load Hash", " "))
+	_, found = g.tables.TT.Probe(1234, 0)
+	assert.True(t, found)
+}
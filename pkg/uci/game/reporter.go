@@ -0,0 +1,90 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/search"
+)
+
+// currMoveDelay is the time after which the current move is reported, so the GUI is not flooded in short searches.
+const currMoveDelay = 3 * time.Second
+
+// uciReporter prints the events of the search in the UCI protocol.
+type uciReporter struct {
+	w io.Writer
+}
+
+func newUCIReporter(w io.Writer) *uciReporter {
+	return &uciReporter{w: w}
+}
+
+func (r *uciReporter) Iteration(e search.IterationEvent) {
+	r.printIteration(e)
+}
+
+func (r *uciReporter) AspirationFail(e search.IterationEvent) {
+	r.printIteration(e)
+}
+
+func (r *uciReporter) printIteration(e search.IterationEvent) {
+	score := scoreString(e.Score)
+	switch e.Bound {
+	case search.LowerBound:
+		score += " lowerbound"
+	case search.UpperBound:
+		score += " upperbound"
+	}
+	if e.WDL != nil {
+		score += fmt.Sprintf(" wdl %v %v %v", e.WDL.Win, e.WDL.Draw, e.WDL.Loss)
+	}
+	fmt.Fprintf(
+		r.w,
+		"info multipv %v depth %v seldepth %v score %v time %v nodes %v nps %v hashfull %v pv %v\n",
+		e.MultiPV,
+		e.Depth,
+		e.SelDepth,
+		score,
+		e.Time.Milliseconds(),
+		e.Nodes,
+		e.NPS,
+		e.HashFull,
+		e.PV,
+	)
+}
+
+func (r *uciReporter) CurrentMove(e search.CurrentMoveEvent) {
+	if e.Time < currMoveDelay {
+		return
+	}
+	fmt.Fprintf(r.w, "info depth %v currmove %v currmovenumber %v\n", e.Depth, e.Move, e.Number)
+}
+
+func (r *uciReporter) BestMove(e search.BestMoveEvent) {
+	// The UCI protocol uses 0000 for a null move, e.g. if there is no move to search
+	if e.Move == move.NullMove {
+		fmt.Fprintln(r.w, "bestmove 0000")
+		return
+	}
+	if e.Ponder != move.NullMove {
+		fmt.Fprintf(r.w, "bestmove %v ponder %v\n", e.Move, e.Ponder)
+		return
+	}
+	fmt.Fprintf(r.w, "bestmove %v\n", e.Move)
+}
+
+func (r *uciReporter) Message(msg string) {
+	fmt.Fprintf(r.w, "info string %v\n", msg)
+}
+
+// scoreString returns the score in the format of the UCI protocol,
+// either in centipawns or as number of moves until a checkmate.
+func scoreString(score int16) string {
+	if evaluation.IsCheckmateValue(score) {
+		return fmt.Sprintf("mate %v", evaluation.MateIn(score))
+	}
+	return fmt.Sprintf("cp %v", score)
+}
@@ -0,0 +1,124 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shaardie/clemens/pkg/evaluation"
+	"github.com/shaardie/clemens/pkg/move"
+	"github.com/shaardie/clemens/pkg/search"
+	"github.com/shaardie/clemens/pkg/search/pvline"
+	"github.com/shaardie/clemens/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_uciReporter(t *testing.T) {
+	var m, ponder move.Move
+	m.SetSourceSquare(types.SQUARE_E2)
+	m.SetTargetSquare(types.SQUARE_E4)
+	ponder.SetSourceSquare(types.SQUARE_E7)
+	ponder.SetTargetSquare(types.SQUARE_E5)
+	pv := pvline.New(m)
+	iteration := search.IterationEvent{
+		MultiPV:  1,
+		Depth:    5,
+		SelDepth: 9,
+		Score:    42,
+		Time:     1500 * time.Millisecond,
+		Nodes:    3000,
+		NPS:      2000,
+		HashFull: 12,
+		PV:       pv,
+	}
+
+	tests := []struct {
+		name   string
+		report func(r *uciReporter)
+		want   string
+	}{
+		{
+			name:   "iteration",
+			report: func(r *uciReporter) { r.Iteration(iteration) },
+			want:   "info multipv 1 depth 5 seldepth 9 score cp 42 time 1500 nodes 3000 nps 2000 hashfull 12 pv e2e4\n",
+		},
+		{
+			name: "iteration with wdl",
+			report: func(r *uciReporter) {
+				i := iteration
+				i.WDL = &search.WDL{Win: 100, Draw: 850, Loss: 50}
+				r.Iteration(i)
+			},
+			want: "info multipv 1 depth 5 seldepth 9 score cp 42 wdl 100 850 50 time 1500 nodes 3000 nps 2000 hashfull 12 pv e2e4\n",
+		},
+		{
+			name: "aspiration fail",
+			report: func(r *uciReporter) {
+				i := iteration
+				i.Bound = search.LowerBound
+				r.AspirationFail(i)
+			},
+			want: "info multipv 1 depth 5 seldepth 9 score cp 42 lowerbound time 1500 nodes 3000 nps 2000 hashfull 12 pv e2e4\n",
+		},
+		{
+			name: "current move too early",
+			report: func(r *uciReporter) {
+				r.CurrentMove(search.CurrentMoveEvent{Depth: 3, Move: m, Number: 1, Time: time.Second})
+			},
+			want: "",
+		},
+		{
+			name: "current move",
+			report: func(r *uciReporter) {
+				r.CurrentMove(search.CurrentMoveEvent{Depth: 3, Move: m, Number: 2, Time: 4 * time.Second})
+			},
+			want: "info depth 3 currmove e2e4 currmovenumber 2\n",
+		},
+		{
+			name:   "best move",
+			report: func(r *uciReporter) { r.BestMove(search.BestMoveEvent{Move: m}) },
+			want:   "bestmove e2e4\n",
+		},
+		{
+			name:   "best move with ponder",
+			report: func(r *uciReporter) { r.BestMove(search.BestMoveEvent{Move: m, Ponder: ponder}) },
+			want:   "bestmove e2e4 ponder e7e5\n",
+		},
+		{
+			name:   "null move",
+			report: func(r *uciReporter) { r.BestMove(search.BestMoveEvent{Move: move.NullMove}) },
+			want:   "bestmove 0000\n",
+		},
+		{
+			name:   "message",
+			report: func(r *uciReporter) { r.Message("hello") },
+			want:   "info string hello\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			tt.report(newUCIReporter(&b))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func Test_scoreString(t *testing.T) {
+	tests := []struct {
+		name  string
+		score int16
+		want  string
+	}{
+		{name: "centipawns", score: 42, want: "cp 42"},
+		{name: "negative centipawns", score: -42, want: "cp -42"},
+		{name: "mate in 1", score: evaluation.INF - 1, want: "mate 1"},
+		{name: "mate in 2", score: evaluation.INF - 3, want: "mate 2"},
+		{name: "mated in 1", score: -evaluation.INF + 2, want: "mate -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, scoreString(tt.score))
+		})
+	}
+}
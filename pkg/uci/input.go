@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/shaardie/clemens/pkg/metadata"
-	"github.com/shaardie/clemens/pkg/uci/game"
 )
 
 func handleInput(input string) {
@@ -19,7 +18,9 @@ func handleInput(input string) {
 	tokens = tokens[1:]
 	switch baseCmd {
 	case "uci":
-		fmt.Printf("id name %v %v\nid author %v\nuciok\n", metadata.Name, metadata.Version, metadata.Author)
+		fmt.Printf("id name %v %v\nid author %v\n", metadata.Name, metadata.Version, metadata.Author)
+		g.PrintOptions()
+		fmt.Println("uciok")
 		return
 	case "quit":
 		os.Exit(0)
@@ -27,15 +28,18 @@ func handleInput(input string) {
 		g.IsReady()
 		return
 	case "ucinewgame":
-		g = game.New()
-		// transpositiontable.Reset()
+		g.NewGame()
 		return
+	case "setoption":
+		g.SetOption(tokens)
 	case "position":
 		g.NewPosition(tokens)
 	case "go":
-		go g.StartSearch(tokens)
+		g.StartSearch(tokens)
 	case "stop":
 		g.StopSearch()
+	case "ponderhit":
+		g.PonderHit()
 	}
 }
 
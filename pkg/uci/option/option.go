@@ -0,0 +1,184 @@
+package option
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of an UCI option
+type Type string
+
+const (
+	SPIN   Type = "spin"
+	CHECK  Type = "check"
+	COMBO  Type = "combo"
+	BUTTON Type = "button"
+	STRING Type = "string"
+)
+
+// Option is a single UCI option, which can be changed by the GUI with the setoption command.
+type Option struct {
+	Name    string
+	Type    Type
+	Default string
+	Min     int
+	Max     int
+	Vars    []string
+
+	// set validates the value and calls the change callback
+	set func(value string) error
+}
+
+// NewSpin creates an integer option in the range from min to max.
+func NewSpin(name string, def, min, max int, onChange func(int)) *Option {
+	return &Option{
+		Name:    name,
+		Type:    SPIN,
+		Default: strconv.Itoa(def),
+		Min:     min,
+		Max:     max,
+		set: func(value string) error {
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("value %v is no integer", value)
+			}
+			if v < min || v > max {
+				return fmt.Errorf("value %v is not in [%v,%v]", v, min, max)
+			}
+			onChange(v)
+			return nil
+		},
+	}
+}
+
+// NewCheck creates a boolean option.
+func NewCheck(name string, def bool, onChange func(bool)) *Option {
+	return &Option{
+		Name:    name,
+		Type:    CHECK,
+		Default: strconv.FormatBool(def),
+		set: func(value string) error {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("value %v is no boolean", value)
+			}
+			onChange(v)
+			return nil
+		},
+	}
+}
+
+// NewCombo creates an option, which can only be set to one of the predefined values.
+func NewCombo(name string, def string, vars []string, onChange func(string)) *Option {
+	return &Option{
+		Name:    name,
+		Type:    COMBO,
+		Default: def,
+		Vars:    vars,
+		set: func(value string) error {
+			idx := slices.IndexFunc(vars, func(v string) bool { return strings.EqualFold(v, value) })
+			if idx < 0 {
+				return fmt.Errorf("value %v is not one of %v", value, strings.Join(vars, ", "))
+			}
+			onChange(vars[idx])
+			return nil
+		},
+	}
+}
+
+// NewButton creates an option without a value, which triggers an action.
+func NewButton(name string, onPress func()) *Option {
+	return &Option{
+		Name: name,
+		Type: BUTTON,
+		set: func(string) error {
+			onPress()
+			return nil
+		},
+	}
+}
+
+// NewString creates an option with an arbitrary text as value.
+func NewString(name string, def string, onChange func(string)) *Option {
+	return &Option{
+		Name:    name,
+		Type:    STRING,
+		Default: def,
+		set: func(value string) error {
+			onChange(value)
+			return nil
+		},
+	}
+}
+
+// String returns the option in the format of the UCI protocol
+func (o *Option) String() string {
+	r := fmt.Sprintf("option name %v type %v", o.Name, o.Type)
+	switch o.Type {
+	case SPIN:
+		r = fmt.Sprintf("%v default %v min %v max %v", r, o.Default, o.Min, o.Max)
+	case COMBO:
+		r = fmt.Sprintf("%v default %v", r, o.Default)
+		for _, v := range o.Vars {
+			r = fmt.Sprintf("%v var %v", r, v)
+		}
+	case CHECK:
+		r = fmt.Sprintf("%v default %v", r, o.Default)
+	case STRING:
+		def := o.Default
+		// Empty strings have a special representation in the UCI protocol
+		if def == "" {
+			def = "<empty>"
+		}
+		r = fmt.Sprintf("%v default %v", r, def)
+	}
+	return r
+}
+
+// Registry holds all options of the engine in the order in which they are advertised.
+type Registry struct {
+	options []*Option
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add adds the options to the registry and applies their default values.
+func (r *Registry) Add(options ...*Option) {
+	for _, o := range options {
+		r.options = append(r.options, o)
+		if o.Type != BUTTON {
+			// The default values are valid by definition
+			_ = o.set(o.Default)
+		}
+	}
+}
+
+// Set sets the value of the option with the name. The name is case insensitive.
+func (r *Registry) Set(name, value string) error {
+	idx := slices.IndexFunc(r.options, func(o *Option) bool { return strings.EqualFold(o.Name, name) })
+	if idx < 0 {
+		return fmt.Errorf("unknown option %v", name)
+	}
+	o := r.options[idx]
+	if o.Type == STRING && value == "<empty>" {
+		value = ""
+	}
+	if o.Type != BUTTON && o.Type != STRING && value == "" {
+		return errors.New("value missing")
+	}
+	return o.set(value)
+}
+
+// String returns all options in the format of the UCI protocol
+func (r *Registry) String() string {
+	lines := make([]string, len(r.options))
+	for i, o := range r.options {
+		lines[i] = o.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,78 @@
+package option
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Set(t *testing.T) {
+	var spin int
+	var check bool
+	var combo, str string
+	var pressed bool
+	r := NewRegistry()
+	r.Add(
+		NewSpin("Threads", 1, 1, 8, func(v int) { spin = v }),
+		NewCheck("Ponder", true, func(v bool) { check = v }),
+		NewCombo("Style", "Normal", []string{"Solid", "Normal", "Risky"}, func(v string) { combo = v }),
+		NewButton("Clear Hash", func() { pressed = true }),
+		NewString("Book File", "", func(v string) { str = v }),
+	)
+
+	// Defaults are applied
+	assert.Equal(t, 1, spin)
+	assert.True(t, check)
+	assert.Equal(t, "Normal", combo)
+	assert.False(t, pressed)
+
+	tests := []struct {
+		name    string
+		option  string
+		value   string
+		wantErr bool
+	}{
+		{name: "spin", option: "threads", value: "4"},
+		{name: "spin too big", option: "Threads", value: "9", wantErr: true},
+		{name: "spin no integer", option: "Threads", value: "many", wantErr: true},
+		{name: "check", option: "Ponder", value: "false"},
+		{name: "check no boolean", option: "Ponder", value: "maybe", wantErr: true},
+		{name: "combo", option: "Style", value: "risky"},
+		{name: "combo unknown", option: "Style", value: "Crazy", wantErr: true},
+		{name: "button", option: "Clear Hash"},
+		{name: "string", option: "Book File", value: "book.bin"},
+		{name: "unknown option", option: "Hash", value: "16", wantErr: true},
+		{name: "missing value", option: "Threads", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Set(tt.option, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, 4, spin)
+	assert.False(t, check)
+	assert.Equal(t, "Risky", combo)
+	assert.True(t, pressed)
+	assert.Equal(t, "book.bin", str)
+}
+
+func TestRegistry_String(t *testing.T) {
+	r := NewRegistry()
+	r.Add(
+		NewSpin("Threads", 1, 1, 8, func(int) {}),
+		NewCheck("Ponder", false, func(bool) {}),
+		NewCombo("Style", "Normal", []string{"Solid", "Normal"}, func(string) {}),
+		NewButton("Clear Hash", func() {}),
+		NewString("Book File", "", func(string) {}),
+	)
+	assert.Equal(t, `option name Threads type spin default 1 min 1 max 8
+option name Ponder type check default false
+option name Style type combo default Normal var Solid var Normal
+option name Clear Hash type button
+option name Book File type string default <empty>`, r.String())
+}
@@ -1,16 +1,20 @@
 #!/bin/bash
 
 set -eux
-ELO="2300"
+# Elo of stockfish, which is the anchor for the rating of clemens
+ELO="${ELO:-2300}"
+PGN="${PGN:-output_pgn_file.pgn}"
+# Options for clemens, e.g. "option.UCI_LimitStrength=true option.UCI_Elo=1500"
+CLEMENS_OPTIONS="${CLEMENS_OPTIONS:-}"
 
 c-chess-cli \
     -each tc=40/1+0.05 \
-    -engine cmd=clemens \
+    -engine cmd=clemens $CLEMENS_OPTIONS \
     -engine name=stockfish cmd=stockfish option.UCI_LimitStrength=true "option.UCI_Elo=$ELO" \
     -openings file=/openings/UHO_XXL_2022_+120_+149.epd order=random -repeat \
     -resign count=4 score=1000 \
     -draw number=40 count=8 score=10 \
-    -sprt -pgn output_pgn_file.pgn \
+    -sprt -pgn "$PGN" \
     -games 400 -concurrency 3
 
-ordo -D -W -a "$ELO" -A stockfish -p output_pgn_file.pgn
+ordo -D -W -a "$ELO" -A stockfish -p "$PGN"
@@ -0,0 +1,20 @@
+#!/bin/bash
+
+# Measures the playing strength of every skill level for the calibration of search.EloToSkillLevel.
+# Clemens plays with the lowest `UCI_Elo` of the level against stockfish with the same `UCI_Elo`,
+# so the rating reported by ordo is the real strength of the level.
+# Stockfish does not play below 1320, so it is the anchor for all lower levels.
+
+set -eux
+MIN_ELO=1000
+MAX_ELO=2400
+MAX_SKILL_LEVEL=20
+STOCKFISH_MIN_ELO=1320
+
+for level in $(seq 0 $((MAX_SKILL_LEVEL - 1))); do
+    elo=$((MIN_ELO + (level * (MAX_ELO - MIN_ELO) + MAX_SKILL_LEVEL - 1) / MAX_SKILL_LEVEL))
+    ELO=$((elo > STOCKFISH_MIN_ELO ? elo : STOCKFISH_MIN_ELO)) \
+    PGN="skill_level_$level.pgn" \
+    CLEMENS_OPTIONS="option.UCI_LimitStrength=true option.UCI_Elo=$elo" \
+    "$(dirname "$0")/elo.sh"
+done